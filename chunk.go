@@ -0,0 +1,196 @@
+package gwc
+
+import "strconv"
+
+// ChunkCoord addresses a chunk of a ChunkManager's world.
+type ChunkCoord struct {
+	X, Y int
+}
+
+// CellStateFn provides the NodeStateFn of the cell at the given world coordinates.
+// The cell's Node has the ID TiledNodeID(x, y) and its neighbours are the adjacent cells in the four directions.
+type CellStateFn func(x, y int) NodeStateFn
+
+// ChunkManager generates an infinite grid world in square chunks on demand.
+//
+// Neighbouring chunks share the cells of their common border, the seams. Before a chunk is generated, its corners and seams are
+// generated on their own and then pinned as locked states within the chunk, so that the chunk continues them seamlessly.
+// Corners, seams and chunks use random streams derived from the world seed and their coordinates only,
+// which is why every chunk is generated the same way, no matter in which order the chunks are visited.
+type ChunkManager struct {
+	size  int
+	seed  uint64
+	order CollapseOrderFn
+	cell  CellStateFn
+
+	chunks    map[ChunkCoord]NodeEnvironment
+	seams     NodeStatesMap
+	generated map[string]bool
+}
+
+// Builds a ChunkManager, whose chunks span size cells in each direction, not counting the seam they share with their neighbours.
+// A size below 1 is raised to 1.
+func NewChunkManager(size int, seed uint64, order CollapseOrderFn, cell CellStateFn) *ChunkManager {
+	if size < 1 {
+		size = 1
+	}
+	return &ChunkManager{
+		size:      size,
+		seed:      seed,
+		order:     order,
+		cell:      cell,
+		chunks:    map[ChunkCoord]NodeEnvironment{},
+		seams:     NodeStatesMap{},
+		generated: map[string]bool{},
+	}
+}
+
+// Derives the seed of a chunk from the world seed and the chunk's coordinates.
+func ChunkSeed(seed uint64, cx, cy int) uint64 {
+	return NodeSeed(seed, "chunk:"+strconv.Itoa(cx)+":"+strconv.Itoa(cy), 0)
+}
+
+// Returns the coordinates of the chunk containing the cell. Cells on a seam belong to the chunk to their right or bottom.
+func (m *ChunkManager) ChunkAt(x, y int) ChunkCoord {
+	return ChunkCoord{floorDiv(x, m.size), floorDiv(y, m.size)}
+}
+
+// Returns the environment of the chunk, generating it first if necessary.
+// It contains the chunk's cells from its top left corner to its bottom right corner, including the seams on all four sides.
+func (m *ChunkManager) Chunk(cx, cy int) NodeEnvironment {
+	coord := ChunkCoord{cx, cy}
+	if env, generated := m.chunks[coord]; generated {
+		return env
+	}
+
+	x0, y0 := cx*m.size, cy*m.size
+	m.seam(x0, y0, true)
+	m.seam(x0, y0, false)
+	m.seam(x0, y0+m.size, true)
+	m.seam(x0+m.size, y0, false)
+
+	env := m.generate(ChunkSeed(m.seed, cx, cy), x0, y0, m.size+1, m.size+1, m.seams)
+	m.chunks[coord] = env
+	return env
+}
+
+// Returns whether the chunk has been generated and not unloaded since.
+func (m *ChunkManager) IsGenerated(cx, cy int) bool {
+	_, generated := m.chunks[ChunkCoord{cx, cy}]
+	return generated
+}
+
+// Drops the chunk. It will be generated the same way again when it's requested the next time.
+// Its seams and corners are dropped as well, unless another loaded chunk shares them.
+func (m *ChunkManager) Unload(cx, cy int) {
+	delete(m.chunks, ChunkCoord{cx, cy})
+
+	x0, y0 := cx*m.size, cy*m.size
+	m.evictSeam(x0, y0, true, ChunkCoord{cx, cy - 1})
+	m.evictSeam(x0, y0, false, ChunkCoord{cx - 1, cy})
+	m.evictSeam(x0, y0+m.size, true, ChunkCoord{cx, cy + 1})
+	m.evictSeam(x0+m.size, y0, false, ChunkCoord{cx + 1, cy})
+	for _, c := range [4]ChunkCoord{{cx, cy}, {cx + 1, cy}, {cx, cy + 1}, {cx + 1, cy + 1}} {
+		m.evictCorner(c.X, c.Y)
+	}
+}
+
+// Returns the state of the cell, generating its chunk first if necessary.
+func (m *ChunkManager) State(x, y int) NodeState {
+	coord := m.ChunkAt(x, y)
+	env := m.Chunk(coord.X, coord.Y)
+	return env.StatesMap[TiledNodeID(x, y)]
+}
+
+// Generates the seam starting at the corner, which runs to the right or down to the next corner. Both corners are generated first.
+func (m *ChunkManager) seam(x, y int, horizontal bool) {
+	m.corner(x, y)
+	w, h := 1, m.size+1
+	if horizontal {
+		w, h = m.size+1, 1
+		m.corner(x+m.size, y)
+	} else {
+		m.corner(x, y+m.size)
+	}
+	name := seamName(x, y, horizontal)
+	if m.generated[name] {
+		return
+	}
+	m.generated[name] = true
+	env := m.generate(NodeSeed(m.seed, name, 0), x, y, w, h, m.seams)
+	for id, state := range env.StatesMap {
+		m.seams[id] = state
+	}
+}
+
+func (m *ChunkManager) corner(x, y int) {
+	id := TiledNodeID(x, y)
+	if _, generated := m.seams[id]; generated {
+		return
+	}
+	env := m.generate(NodeSeed(m.seed, "corner:"+id, 0), x, y, 1, 1, nil)
+	m.seams[id] = env.StatesMap[id]
+}
+
+// Drops the seam starting at the corner, unless the other chunk sharing it is loaded. Its corners are left to evictCorner().
+func (m *ChunkManager) evictSeam(x, y int, horizontal bool, other ChunkCoord) {
+	if _, loaded := m.chunks[other]; loaded {
+		return
+	}
+	delete(m.generated, seamName(x, y, horizontal))
+	for i := 1; i < m.size; i++ {
+		if horizontal {
+			delete(m.seams, TiledNodeID(x+i, y))
+		} else {
+			delete(m.seams, TiledNodeID(x, y+i))
+		}
+	}
+}
+
+// Drops the corner at the top left of the chunk, unless one of the four chunks sharing it is loaded.
+func (m *ChunkManager) evictCorner(cx, cy int) {
+	for _, c := range [4]ChunkCoord{{cx - 1, cy - 1}, {cx, cy - 1}, {cx - 1, cy}, {cx, cy}} {
+		if _, loaded := m.chunks[c]; loaded {
+			return
+		}
+	}
+	delete(m.seams, TiledNodeID(cx*m.size, cy*m.size))
+}
+
+func seamName(x, y int, horizontal bool) string {
+	return "seam:" + TiledNodeID(x, y) + ":" + strconv.FormatBool(horizontal)
+}
+
+// Collapses the rectangle of cells, whose cells within pinned keep their state.
+func (m *ChunkManager) generate(seed uint64, x0, y0, w, h int, pinned NodeStatesMap) NodeEnvironment {
+	nodes := make(Nodes, 0, w*h)
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			neighbours := NodeIDs{}
+			for _, n := range [4][2]int{{x - 1, y}, {x, y + 1}, {x + 1, y}, {x, y - 1}} {
+				if n[0] >= x0 && n[0] < x0+w && n[1] >= y0 && n[1] < y0+h {
+					neighbours = append(neighbours, TiledNodeID(n[0], n[1]))
+				}
+			}
+			nodes = append(nodes, NewNode(TiledNodeID(x, y), m.cell(x, y), neighbours...))
+		}
+	}
+
+	sim := New(NewXoshiro256(seed), m.order, nodes, WithNodeStreams(m.seed))
+	env := sim.Environment()
+	for _, node := range nodes {
+		if state, exists := pinned[node.ID()]; exists {
+			env.SetState(node.ID(), state)
+			env.Lock(node.ID())
+		}
+	}
+	return sim.CollapseEnvironment(env)
+}
+
+// Divides and rounds towards negative infinity, so that negative cells belong to negative chunks.
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
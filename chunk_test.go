@@ -0,0 +1,80 @@
+package gwc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Cells prefer the states of their collapsed neighbours.
+func newChunkTestCell(x, y int) NodeStateFn {
+	prefer := func(state NodeState) NodeSuperpositionFn {
+		return func(_ Random, env NodeEnvironment) (NodeProbability, NodeState) {
+			return NodeProbability(1 + 4*env.CountInState(env.Neighbours(env.Current), state)), state
+		}
+	}
+	return SuperpositionStateFn(NodeSuperposition{prefer("grass"), prefer("water"), prefer("sand")})
+}
+
+func Test_ChunkManager(t *testing.T) {
+	coords := []ChunkCoord{{0, 0}, {1, 0}, {0, 1}, {-1, -1}, {1, 1}, {-1, 0}}
+
+	forward := NewChunkManager(4, 42, RandomCollapseOrder, newChunkTestCell)
+	for _, c := range coords {
+		forward.Chunk(c.X, c.Y)
+	}
+	backward := NewChunkManager(4, 42, RandomCollapseOrder, newChunkTestCell)
+	for i := len(coords) - 1; i >= 0; i-- {
+		backward.Chunk(coords[i].X, coords[i].Y)
+	}
+
+	// Chunks are the same, no matter in which order they're visited.
+	for _, c := range coords {
+		a, b := forward.Chunk(c.X, c.Y), backward.Chunk(c.X, c.Y)
+		assert.Len(t, a.Nodes, 25)
+		assert.Len(t, a.Collapsed(), 25)
+		assert.EqualValues(t, a.States(), b.States())
+	}
+
+	// Neighbouring chunks share their seams.
+	left, right := forward.Chunk(0, 0), forward.Chunk(1, 0)
+	for y := 0; y <= 4; y++ {
+		assert.Equal(t, left.StatesMap[TiledNodeID(4, y)], right.StatesMap[TiledNodeID(4, y)])
+	}
+	assert.Equal(t, forward.State(4, 2), right.StatesMap["4:2"])
+	assert.Equal(t, forward.State(-1, -4), forward.Chunk(-1, -1).StatesMap["-1:-4"])
+
+	// Unloaded chunks are generated the same way again.
+	states := right.States()
+	forward.Unload(1, 0)
+	assert.False(t, forward.IsGenerated(1, 0))
+	regenerated := forward.Chunk(1, 0)
+	assert.EqualValues(t, states, regenerated.States())
+	assert.True(t, forward.IsGenerated(1, 0))
+
+	// Seams and corners are kept as long as a loaded chunk shares them.
+	single := NewChunkManager(4, 42, RandomCollapseOrder, newChunkTestCell)
+	single.Chunk(0, 0)
+	single.Chunk(1, 0)
+	single.Unload(0, 0)
+	assert.Len(t, single.seams, 16)
+	assert.Len(t, single.generated, 4)
+	single.Unload(1, 0)
+	assert.Empty(t, single.seams)
+	assert.Empty(t, single.generated)
+	reloaded := single.Chunk(1, 0)
+	assert.EqualValues(t, states, reloaded.States())
+
+	// Other seeds generate other worlds.
+	other := NewChunkManager(4, 43, RandomCollapseOrder, newChunkTestCell)
+	generated := other.Chunk(0, 0)
+	assert.NotEqual(t, left.States(), generated.States())
+}
+
+func Test_ChunkAt(t *testing.T) {
+	m := NewChunkManager(4, 0, AscendingCollapseOrder, newChunkTestCell)
+	assert.Equal(t, ChunkCoord{0, 0}, m.ChunkAt(3, 0))
+	assert.Equal(t, ChunkCoord{1, -1}, m.ChunkAt(4, -1))
+	assert.Equal(t, ChunkCoord{-2, -1}, m.ChunkAt(-5, -4))
+	assert.NotEqual(t, ChunkSeed(0, 1, 0), ChunkSeed(0, 0, 1))
+}
@@ -0,0 +1,190 @@
+package gwc
+
+import (
+	"sort"
+)
+
+// Returns whether the Node exists and hasn't been collapsed yet.
+func isCollapsible(env NodeEnvironment, id NodeID) bool {
+	if _, exists := env.NodesMap[id]; !exists {
+		return false
+	}
+	_, collapsed := env.CollapsedMap[id]
+	return !collapsed
+}
+
+// subsetView presents a subset of a NodeEnvironment's Nodes as an environment of its own,
+// so that CollapseOrderFns can be restricted to the subset without knowing about it.
+type subsetView struct {
+	set       *NodeIDSet
+	tracker   orderTracker
+	nodes     Nodes
+	nodes_map NodesMap
+	collapsed NodeCollapsedMap
+	last      NodeID
+	// The states of the CollapseOrderFns working on the view, which are kept apart from the ones working on the environment.
+	orders *orderRun
+}
+
+func newSubsetView(set *NodeIDSet) *subsetView {
+	return &subsetView{set: set, collapsed: NodeCollapsedMap{}, orders: newOrderRun()}
+}
+
+// Returns the view of the environment, whose Nodes and CollapsedMap only contain the subset's Nodes.
+// The CollapsedMap is kept across calls, so that stateful CollapseOrderFns can track the view like a regular environment.
+func (v *subsetView) sync(env NodeEnvironment) NodeEnvironment {
+	previously := v.tracker.collapsed
+	if v.tracker.reset(env) {
+		v.nodes = Nodes{}
+		v.nodes_map = NodesMap{}
+		for _, node := range env.Nodes {
+			if id := node.ID(); v.set.Contains(id) {
+				v.nodes = append(v.nodes, node)
+				v.nodes_map[id] = node
+			}
+		}
+		v.resync(env)
+	} else if _, done := env.CollapsedMap[v.last]; done && len(env.CollapsedMap) == previously+1 && v.set.Contains(v.last) {
+		// Only the Node returned last has been collapsed.
+		if _, known := v.collapsed[v.last]; !known {
+			v.collapsed[v.last] = len(v.collapsed)
+		}
+	} else if len(env.CollapsedMap) != previously {
+		v.resync(env)
+	}
+
+	view := env
+	view.Nodes = v.nodes
+	view.NodesMap = v.nodes_map
+	view.CollapsedMap = v.collapsed
+	view.orders = v.orders
+	return view
+}
+
+// Rebuilds the view's CollapsedMap with dense steps, ordered like the environment's steps.
+func (v *subsetView) resync(env NodeEnvironment) {
+	ids := NodeIDs{}
+	for _, node := range v.nodes {
+		if _, collapsed := env.CollapsedMap[node.ID()]; collapsed {
+			ids = append(ids, node.ID())
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return env.CollapsedMap[ids[i]] < env.CollapsedMap[ids[j]]
+	})
+	for id := range v.collapsed {
+		delete(v.collapsed, id)
+	}
+	for step, id := range ids {
+		v.collapsed[id] = step
+	}
+}
+
+// Produces a CollapseOrderFn that only collapses the Nodes with the provided IDs, using the provided order.
+// The order sees an environment that only contains these Nodes, in the order of the original environment. If it returns an ID outside of them,
+// a random Node of the subset is collapsed instead. Once the order returns "", the restricted order does so as well.
+func RestrictedCollapseOrder(order CollapseOrderFn, ids NodeIDs) CollapseOrderFn {
+	key := &orderKey{"restricted"}
+	set := NewNodeIDSet(ids...)
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		view := env.orderState(key, func() interface{} { return newSubsetView(set) }).(*subsetView)
+		sub := view.sync(env)
+		next := order(rnd, sub)
+		if next != "" && !isCollapsible(sub, next) {
+			next = RandomCollapseOrder(rnd, sub)
+		}
+		view.last = next
+		return next
+	}
+}
+
+// Produces a CollapseOrderFn that uses the orders one after another.
+// Once an order returns "" or an ID that can't be collapsed, the chain permanently continues with the next order.
+func ChainCollapseOrder(orders ...CollapseOrderFn) CollapseOrderFn {
+	key := &orderKey{"chain"}
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		state := env.orderState(key, func() interface{} { return &chainState{} }).(*chainState)
+		if state.tracker.reset(env) {
+			state.current = 0
+		}
+		for ; state.current < len(orders); state.current++ {
+			if next := orders[state.current](rnd, env); isCollapsible(env, next) {
+				return next
+			}
+		}
+		return ""
+	}
+}
+
+type chainState struct {
+	tracker orderTracker
+	current int
+}
+
+// Produces a CollapseOrderFn that asks the primary order first and falls back to the other orders, in turn,
+// whenever it returns "" or an ID that can't be collapsed. The primary order is asked again in the next step.
+func FallbackCollapseOrder(primary CollapseOrderFn, fallbacks ...CollapseOrderFn) CollapseOrderFn {
+	orders := append([]CollapseOrderFn{primary}, fallbacks...)
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		for _, order := range orders {
+			if next := order(rnd, env); isCollapsible(env, next) {
+				return next
+			}
+		}
+		return ""
+	}
+}
+
+// CollapseRegion assigns a CollapseOrderFn to a subset of Nodes.
+type CollapseRegion struct {
+	IDs   NodeIDs
+	Order CollapseOrderFn
+}
+
+// Produces a CollapseOrderFn that collapses the regions one after another, each using its own order restricted to its Nodes.
+// Nodes that don't belong to any region are not collapsed; use FallbackCollapseOrder to collapse them as well.
+func RegionCollapseOrder(regions ...CollapseRegion) CollapseOrderFn {
+	orders := make([]CollapseOrderFn, len(regions))
+	for i, region := range regions {
+		orders[i] = RestrictedCollapseOrder(region.Order, region.IDs)
+	}
+	return ChainCollapseOrder(orders...)
+}
+
+// WeightedCollapseOrder assigns a weight to a CollapseOrderFn for MixCollapseOrder.
+type WeightedCollapseOrder struct {
+	Weight float64
+	Order  CollapseOrderFn
+}
+
+// Produces a CollapseOrderFn that chooses one of the orders at random in each step, proportionally to their weights.
+// If the chosen order returns "" or an ID that can't be collapsed, the remaining orders are asked in turn.
+func MixCollapseOrder(orders ...WeightedCollapseOrder) CollapseOrderFn {
+	sum := 0.0
+	for _, o := range orders {
+		sum += o.Weight
+	}
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		if len(orders) == 0 {
+			return ""
+		}
+
+		chosen := len(orders) - 1
+		compare := rnd.Float64() * sum
+		for i, o := range orders {
+			compare -= o.Weight
+			if compare < 0 {
+				chosen = i
+				break
+			}
+		}
+
+		for i := range orders {
+			order := orders[(chosen+i)%len(orders)].Order
+			if next := order(rnd, env); isCollapsible(env, next) {
+				return next
+			}
+		}
+		return ""
+	}
+}
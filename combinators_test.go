@@ -0,0 +1,86 @@
+package gwc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RestrictedCollapseOrder(t *testing.T) {
+	nodes := newDefaultTestNodes()
+	order := RestrictedCollapseOrder(DescendingCollapseOrder, NodeIDs{"1", "3", "5"})
+
+	collapsed := New(rand.New(rand.NewSource(1)), order, nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"5", "3", "1"}, collapsed.Collapsed())
+
+	// Orders returning Nodes outside of the subset are corrected.
+	order = RestrictedCollapseOrder(RandomStreakCollapseOrder, NodeIDs{"2", "4", "6"})
+	collapsed = New(rand.New(rand.NewSource(1)), order, nodes).Collapse()
+	assert.ElementsMatch(t, NodeIDs{"2", "4", "6"}, collapsed.Collapsed())
+}
+
+func Test_ChainCollapseOrder(t *testing.T) {
+	nodes := newDefaultTestNodes()
+	order := ChainCollapseOrder(
+		RestrictedCollapseOrder(AscendingCollapseOrder, NodeIDs{"4", "5", "6"}),
+		FixedCollapseOrder(NodeIDs{"3", "1"}),
+		DescendingCollapseOrder,
+	)
+
+	// The chain can be reused.
+	for _, seed := range []int64{1, 2} {
+		collapsed := New(rand.New(rand.NewSource(seed)), order, nodes).Collapse()
+		assert.EqualValues(t, NodeIDs{"4", "5", "6", "3", "1", "2", "0"}, collapsed.Collapsed())
+	}
+}
+
+func Test_FallbackCollapseOrder(t *testing.T) {
+	nodes := newDefaultTestNodes()
+	order := FallbackCollapseOrder(
+		RestrictedCollapseOrder(AscendingCollapseOrder, NodeIDs{"5", "6"}),
+		DescendingCollapseOrder,
+	)
+
+	collapsed := New(rand.New(rand.NewSource(1)), order, nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"5", "6", "4", "3", "2", "1", "0"}, collapsed.Collapsed())
+}
+
+func Test_RegionCollapseOrder(t *testing.T) {
+	nodes := newDefaultTestNodes()
+	order := RegionCollapseOrder(
+		CollapseRegion{NodeIDs{"0", "1", "2"}, DescendingCollapseOrder},
+		CollapseRegion{NodeIDs{"6", "5", "4"}, AscendingCollapseOrder},
+	)
+
+	// Node 3 isn't part of any region.
+	collapsed := New(rand.New(rand.NewSource(1)), order, nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"2", "1", "0", "4", "5", "6"}, collapsed.Collapsed())
+
+	collapsed = New(rand.New(rand.NewSource(1)), FallbackCollapseOrder(order, RandomCollapseOrder), nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"2", "1", "0", "4", "5", "6", "3"}, collapsed.Collapsed())
+}
+
+func Test_MixCollapseOrder(t *testing.T) {
+	nodes := newDefaultTestNodes()
+
+	// Weightless orders are never chosen.
+	order := MixCollapseOrder(
+		WeightedCollapseOrder{0, AscendingCollapseOrder},
+		WeightedCollapseOrder{1, DescendingCollapseOrder},
+	)
+	collapsed := New(rand.New(rand.NewSource(1)), order, nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"6", "5", "4", "3", "2", "1", "0"}, collapsed.Collapsed())
+
+	order = MixCollapseOrder(
+		WeightedCollapseOrder{1, RestrictedCollapseOrder(AscendingCollapseOrder, NodeIDs{"0", "1", "2"})},
+		WeightedCollapseOrder{1, RestrictedCollapseOrder(AscendingCollapseOrder, NodeIDs{"3", "4", "5", "6"})},
+	)
+	collapsed = New(rand.New(rand.NewSource(1)), order, nodes).Collapse()
+	ids := collapsed.Collapsed()
+	assert.Len(t, ids, 7)
+	assert.EqualValues(t, NodeIDs{"0", "1", "2"}, ids.And(NodeIDs{"0", "1", "2"}))
+	assert.EqualValues(t, NodeIDs{"3", "4", "5", "6"}, ids.And(NodeIDs{"3", "4", "5", "6"}))
+
+	assert.Empty(t, MixCollapseOrder()(nil, *NewNodeEnvironment(nodes)))
+}
@@ -0,0 +1,109 @@
+package gwc
+
+// searchResult holds the outcome of a breadth-first search starting at a single Node.
+type searchResult struct {
+	// order contains the reached Nodes in order of their discovery, which is ordered by distance.
+	order     NodeIDs
+	distances map[NodeID]int
+	parents   map[NodeID]NodeID
+}
+
+// Returns the reached Nodes with a distance of at most depth.
+func (r *searchResult) within(depth int) NodeIDs {
+	ids := NodeIDs{}
+	for _, id := range r.order {
+		if r.distances[id] > depth {
+			break
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// distanceIndex caches complete breadth-first searches per starting Node.
+type distanceIndex struct {
+	searches map[NodeID]*searchResult
+}
+
+// Enables caching of distance queries, which speeds up repeated queries from the same Nodes, e.g. inside NodeSuperpositionFns.
+// The cache is shared by all copies of the environment and has to be reset with ResetDistanceCache() when the graph changes.
+func (ne *NodeEnvironment) EnableDistanceCache() {
+	ne.distances = &distanceIndex{map[NodeID]*searchResult{}}
+}
+
+// Drops all cached distances, if the cache is enabled.
+func (ne *NodeEnvironment) ResetDistanceCache() {
+	if ne.distances != nil {
+		ne.distances.searches = map[NodeID]*searchResult{}
+	}
+}
+
+// Searches breadth-first from the given Node up to the given depth. A negative depth searches the whole graph.
+func (ne *NodeEnvironment) search(id NodeID, depth int) *searchResult {
+	if ne.distances != nil {
+		if cached, exists := ne.distances.searches[id]; exists {
+			return cached
+		}
+		depth = -1
+	}
+
+	r := &searchResult{
+		order:     NodeIDs{id},
+		distances: map[NodeID]int{id: 0},
+		parents:   map[NodeID]NodeID{},
+	}
+	for i := 0; i < len(r.order); i++ {
+		current := r.order[i]
+		d := r.distances[current]
+		if depth >= 0 && d >= depth {
+			break
+		}
+		for _, ni := range ne.Neighbours(current) {
+			if _, seen := r.distances[ni]; !seen {
+				r.order = append(r.order, ni)
+				r.distances[ni] = d + 1
+				r.parents[ni] = current
+			}
+		}
+	}
+
+	if ne.distances != nil {
+		ne.distances.searches[id] = r
+	}
+	return r
+}
+
+// Returns the length of the shortest path leading from Node a to Node b, or -1 if b can't be reached.
+func (ne *NodeEnvironment) Distance(a, b NodeID) int {
+	if d, reached := ne.search(a, -1).distances[b]; reached {
+		return d
+	}
+	return -1
+}
+
+// Returns the Nodes whose shortest path from the given Node has exactly the given length.
+func (ne *NodeEnvironment) NodesAtDistance(id NodeID, distance uint) NodeIDs {
+	r := ne.search(id, int(distance))
+	ids := NodeIDs{}
+	for _, ni := range r.order {
+		if r.distances[ni] == int(distance) {
+			ids = append(ids, ni)
+		}
+	}
+	return ids
+}
+
+// Returns one of the shortest paths leading from Node a to Node b, including both, or nil if b can't be reached.
+func (ne *NodeEnvironment) ShortestPath(a, b NodeID) NodeIDs {
+	r := ne.search(a, -1)
+	d, reached := r.distances[b]
+	if !reached {
+		return nil
+	}
+	path := make(NodeIDs, d+1)
+	for id := b; d >= 0; d-- {
+		path[d] = id
+		id = r.parents[id]
+	}
+	return path
+}
@@ -0,0 +1,68 @@
+package gwc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newShortcutTestNodesEnvironment() *NodeEnvironment {
+	// The graph has the following form, where "a" lists its long way around first:
+	// a - b - c - d
+	// |           |
+	// +---- e ----+
+	return NewNodeEnvironment(Nodes{
+		NewNode("a", nil, "b", "e"),
+		NewNode("b", nil, "a", "c"),
+		NewNode("c", nil, "b", "d"),
+		NewNode("d", nil, "c", "e"),
+		NewNode("e", nil, "a", "d"),
+	})
+}
+
+func Test_Distance(t *testing.T) {
+	for _, cached := range []bool{false, true} {
+		ne := newShortcutTestNodesEnvironment()
+		if cached {
+			ne.EnableDistanceCache()
+		}
+
+		assert.Equal(t, 0, ne.Distance("a", "a"))
+		assert.Equal(t, 2, ne.Distance("a", "d"))
+		assert.Equal(t, 2, ne.Distance("a", "c"))
+		assert.Equal(t, -1, ne.Distance("a", "x"))
+
+		assert.True(t, ne.IsWithinRangeOf("a", "d", 2))
+		assert.False(t, ne.IsWithinRangeOf("a", "d", 1))
+		assert.EqualValues(t, NodeIDs{"a", "b", "e", "c", "d"}, ne.NodesWithinRangeOfIncl("a", 2))
+		assert.EqualValues(t, NodeIDs{"b", "e"}, ne.NodesWithinRangeOfExcl("a", 1))
+
+		assert.EqualValues(t, NodeIDs{"a"}, ne.NodesAtDistance("a", 0))
+		assert.EqualValues(t, NodeIDs{"c", "d"}, ne.NodesAtDistance("a", 2))
+		assert.Empty(t, ne.NodesAtDistance("a", 3))
+
+		assert.EqualValues(t, NodeIDs{"a", "e", "d"}, ne.ShortestPath("a", "d"))
+		assert.EqualValues(t, NodeIDs{"c"}, ne.ShortestPath("c", "c"))
+		assert.Nil(t, ne.ShortestPath("a", "x"))
+	}
+}
+
+func Test_DistanceCache(t *testing.T) {
+	ne := newShortcutTestNodesEnvironment()
+	ne.EnableDistanceCache()
+	copied := *ne
+
+	assert.Equal(t, 2, copied.Distance("a", "d"))
+	assert.Len(t, ne.distances.searches, 1)
+
+	ne.ResetDistanceCache()
+	assert.Empty(t, ne.distances.searches)
+}
+
+func Test_WithDistanceCache(t *testing.T) {
+	sim := New(nil, AscendingCollapseOrder, newDefaultTestNodes(), WithDistanceCache())
+	env := sim.Collapse()
+
+	assert.NotNil(t, env.distances)
+	assert.Equal(t, 3, env.Distance("0", "6"))
+}
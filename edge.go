@@ -0,0 +1,215 @@
+package gwc
+
+import "sort"
+
+// Edge connects two Nodes. In undirected environments an Edge and its reverse are the same Edge,
+// which is always reported in the direction it was first listed in.
+type Edge struct {
+	From, To NodeID
+}
+
+type EdgeStatesMap = map[Edge]NodeState
+type EdgeCollapsedMap = map[Edge]int
+
+// EdgeStateFns provides the NodeStateFn an Edge collapses with, or nil for Edges without states, which collapse into nil.
+// Edge state functions can find the Edge being collapsed in NodeEnvironment.CurrentEdge.
+type EdgeStateFns = func(Edge) NodeStateFn
+
+// EdgeCollapseOrderFn is a function that takes the current NodeEnvironment and returns the next Edge to be collapsed.
+// It returns the zero Edge if no Edge should be collapsed at the moment, so that the next Node gets collapsed instead.
+type EdgeCollapseOrderFn func(Random, NodeEnvironment) Edge
+
+func (e Edge) String() string {
+	return e.From + "->" + e.To
+}
+
+// Returns the Edge pointing in the other direction.
+func (e Edge) Reverse() Edge {
+	return Edge{e.To, e.From}
+}
+
+// Returns whether the Node is one of the Edge's ends.
+func (e Edge) Touches(id NodeID) bool {
+	return e.From == id || e.To == id
+}
+
+// Lists the Edges of the Nodes, in order of the Nodes and their neighbours. Reverse Edges are omitted in undirected mode.
+func buildEdgeIndex(nodes Nodes, neighbours func(NodeID) NodeIDs, nodes_map NodesMap, undirected bool) ([]Edge, map[Edge]int) {
+	edges := []Edge{}
+	index := map[Edge]int{}
+	for _, node := range nodes {
+		for _, ni := range neighbours(node.ID()) {
+			edge := Edge{node.ID(), ni}
+			if _, exists := nodes_map[ni]; !exists {
+				continue
+			}
+			if _, indexed := index[edge]; indexed {
+				continue
+			}
+			index[edge] = len(edges)
+			if undirected {
+				index[edge.Reverse()] = len(edges)
+			}
+			edges = append(edges, edge)
+		}
+	}
+	return edges, index
+}
+
+// Returns all Edges between the Nodes, in order of the Nodes and their neighbours. Edges added later on are appended.
+func (ne *NodeEnvironment) Edges() []Edge {
+	if ne.edges == nil {
+		ne.edges, ne.edgeIndex = buildEdgeIndex(ne.Nodes, ne.Neighbours, ne.NodesMap, ne.Undirected)
+	}
+	return ne.edges
+}
+
+// Returns the Edge as it's stored by the environment, which is either the Edge or, in undirected mode, its reverse.
+func (ne *NodeEnvironment) edgeKey(edge Edge) (Edge, bool) {
+	edges := ne.Edges()
+	if idx, exists := ne.edgeIndex[edge]; exists {
+		return edges[idx], true
+	}
+	return edge, false
+}
+
+func (ne *NodeEnvironment) HasEdge(from, to NodeID) bool {
+	_, exists := ne.edgeKey(Edge{from, to})
+	return exists
+}
+
+// Returns the Edges that start or end at the Node, in order of the environment's Edges.
+func (ne *NodeEnvironment) IncidentEdges(id NodeID) []Edge {
+	edges := []Edge{}
+	for _, edge := range ne.Edges() {
+		if edge.Touches(id) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+func (ne *NodeEnvironment) IsEdgeCollapsed(from, to NodeID) bool {
+	edge, _ := ne.edgeKey(Edge{from, to})
+	_, collapsed := ne.EdgeCollapsedMap[edge]
+	return collapsed
+}
+
+// Returns the state of the Edge, or nil if it's uncollapsed or doesn't exist.
+func (ne *NodeEnvironment) EdgeState(from, to NodeID) NodeState {
+	edge, _ := ne.edgeKey(Edge{from, to})
+	return ne.EdgeStatesMap[edge]
+}
+
+// Returns the collapsed Edges, in the order they were collapsed in.
+func (ne *NodeEnvironment) CollapsedEdges() []Edge {
+	edges := make([]Edge, len(ne.EdgeCollapsedMap))
+	for edge, at := range ne.EdgeCollapsedMap {
+		edges[at] = edge
+	}
+	return edges
+}
+
+// Returns the collapsed Edges in the given state, in order of the environment's Edges.
+func (ne *NodeEnvironment) EdgesInState(state NodeState) []Edge {
+	edges := []Edge{}
+	for _, edge := range ne.Edges() {
+		if _, collapsed := ne.EdgeCollapsedMap[edge]; collapsed && StatesEqual(ne.EdgeStatesMap[edge], state) {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// Returns the IDs of the Nodes reachable from the Node, including itself, by only traversing Edges accepted by the function.
+// The function receives the state of each Edge, which is nil for uncollapsed Edges. The IDs are ordered by their distance.
+func (ne *NodeEnvironment) ReachableVia(id NodeID, passable func(Edge, NodeState) bool) NodeIDs {
+	if _, exists := ne.NodesMap[id]; !exists {
+		return NodeIDs{}
+	}
+	visited := NewNodeIDSet(id)
+	queue := NodeIDs{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, ni := range ne.Neighbours(current) {
+			if visited.Contains(ni) {
+				continue
+			}
+			if edge, exists := ne.edgeKey(Edge{current, ni}); exists && passable(edge, ne.EdgeStatesMap[edge]) {
+				visited.Add(ni)
+				queue = append(queue, ni)
+			}
+		}
+	}
+	return visited.IDs()
+}
+
+// Removes the states of the Edges and renumbers the collapse steps of the remaining Edges, so that they stay dense.
+func (ne *NodeEnvironment) uncollapseEdges(edges ...Edge) {
+	removed := false
+	for _, edge := range edges {
+		edge, _ = ne.edgeKey(edge)
+		if _, collapsed := ne.EdgeCollapsedMap[edge]; collapsed {
+			delete(ne.EdgeCollapsedMap, edge)
+			removed = true
+		}
+		delete(ne.EdgeStatesMap, edge)
+	}
+	if !removed {
+		return
+	}
+	steps := make([]Edge, 0, len(ne.EdgeCollapsedMap))
+	for edge := range ne.EdgeCollapsedMap {
+		steps = append(steps, edge)
+	}
+	sort.Slice(steps, func(i, j int) bool { return ne.EdgeCollapsedMap[steps[i]] < ne.EdgeCollapsedMap[steps[j]] })
+	for at, edge := range steps {
+		ne.EdgeCollapsedMap[edge] = at
+	}
+}
+
+func isEdgeCollapsible(env NodeEnvironment, edge Edge) bool {
+	if edge == (Edge{}) {
+		return false
+	}
+	key, exists := env.edgeKey(edge)
+	if !exists {
+		return false
+	}
+	_, collapsed := env.EdgeCollapsedMap[key]
+	return !collapsed
+}
+
+// Collapses all Edges in ascending order before any Node is collapsed.
+var AscendingEdgeCollapseOrder EdgeCollapseOrderFn = func(rnd Random, env NodeEnvironment) Edge {
+	for _, edge := range env.Edges() {
+		if _, collapsed := env.EdgeCollapsedMap[edge]; !collapsed {
+			return edge
+		}
+	}
+	return Edge{}
+}
+
+// Collapses each Edge as soon as both of its Nodes are collapsed, so that Edge state functions can read the states of both ends.
+var ConnectedEdgeCollapseOrder EdgeCollapseOrderFn = func(rnd Random, env NodeEnvironment) Edge {
+	for _, edge := range env.Edges() {
+		if _, collapsed := env.EdgeCollapsedMap[edge]; collapsed {
+			continue
+		}
+		_, from := env.CollapsedMap[edge.From]
+		_, to := env.CollapsedMap[edge.To]
+		if from && to {
+			return edge
+		}
+	}
+	return Edge{}
+}
+
+// Collapses the Edges in ascending order once all Nodes are collapsed.
+var DeferredEdgeCollapseOrder EdgeCollapseOrderFn = func(rnd Random, env NodeEnvironment) Edge {
+	if len(env.CollapsedMap) < len(env.Nodes) {
+		return Edge{}
+	}
+	return AscendingEdgeCollapseOrder(rnd, env)
+}
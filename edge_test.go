@@ -0,0 +1,73 @@
+package gwc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Edges(t *testing.T) {
+	directed := NewNodeEnvironment(newLinearNodes())
+	assert.Len(t, directed.Edges(), 6)
+	assert.True(t, directed.HasEdge("1", "0"))
+	assert.False(t, directed.HasEdge("0", "2"))
+
+	env := NewUndirectedNodeEnvironment(Nodes{NewNode("0", nil, "1"), NewNode("1", nil, "2"), NewNode("2", nil)})
+	assert.Equal(t, []Edge{{"0", "1"}, {"1", "2"}}, env.Edges())
+	assert.True(t, env.HasEdge("2", "1"))
+	assert.Equal(t, []Edge{{"0", "1"}, {"1", "2"}}, env.IncidentEdges("1"))
+
+	env.EdgeStatesMap[Edge{"1", "2"}] = "wall"
+	env.EdgeCollapsedMap[Edge{"1", "2"}] = 0
+	assert.True(t, env.IsEdgeCollapsed("2", "1"))
+	assert.Equal(t, "wall", env.EdgeState("2", "1"))
+	assert.Nil(t, env.EdgeState("0", "1"))
+	assert.Equal(t, []Edge{{"1", "2"}}, env.EdgesInState("wall"))
+
+	open := func(_ Edge, state NodeState) bool { return state != "wall" }
+	assert.EqualValues(t, NodeIDs{"0", "1"}, env.ReachableVia("0", open))
+	assert.EqualValues(t, NodeIDs{"2"}, env.ReachableVia("2", open))
+}
+
+func Test_EdgeStates(t *testing.T) {
+	// A corridor of rooms, whose doors may only be locked if the key room can be reached from the start without passing a locked door.
+	rooms := NodeSuperposition{
+		func(_ Random, env NodeEnvironment) (NodeProbability, NodeState) {
+			if env.Current == "3" {
+				return 1, "key"
+			}
+			return 1, "room"
+		},
+	}
+	doors := func(edge Edge) NodeStateFn {
+		return func(_ Random, env NodeEnvironment) NodeState {
+			reachable := env.ReachableVia("0", func(_ Edge, state NodeState) bool { return state != "locked" })
+			for _, id := range reachable {
+				if env.StatesMap[id] == "key" && !env.CurrentEdge.Touches(id) {
+					return "locked"
+				}
+			}
+			return "door"
+		}
+	}
+	nodes := newLinearNodes(rooms...)
+
+	sim := New(rand.New(rand.NewSource(42)), DescendingCollapseOrder, nodes, WithUndirectedEdges(), WithEdgeStates(doors, nil))
+	env := sim.Collapse()
+	assert.Len(t, env.CollapsedEdges(), 3)
+	assert.Equal(t, []Edge{{"2", "3"}, {"1", "2"}, {"0", "1"}}, env.CollapsedEdges())
+	assert.Equal(t, []Edge{{"1", "2"}}, env.EdgesInState("locked"))
+	assert.Equal(t, "door", env.EdgeState("3", "2"))
+
+	// Edges are rerolled along with their Nodes.
+	rerolled := sim.Reroll(env, NodeIDs{"3"}, 0)
+	assert.Equal(t, []Edge{{"1", "2"}, {"0", "1"}, {"2", "3"}}, rerolled.CollapsedEdges())
+	assert.Len(t, env.CollapsedEdges(), 3)
+
+	// Edges can be collapsed before or after all Nodes.
+	env = New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, nodes, WithEdgeStates(doors, AscendingEdgeCollapseOrder)).Collapse()
+	assert.Len(t, env.EdgesInState("door"), 6)
+	env = New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, nodes, WithEdgeStates(doors, DeferredEdgeCollapseOrder)).Collapse()
+	assert.Equal(t, []Edge{{"0", "1"}}, env.EdgesInState("locked"))
+}
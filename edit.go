@@ -0,0 +1,88 @@
+package gwc
+
+import "sort"
+
+// Removes the state of the Node and renumbers the collapse steps of the other Nodes, so that they stay dense.
+// Returns false if the Node isn't collapsed or is locked.
+func (ne *NodeEnvironment) Uncollapse(id NodeID) bool {
+	if _, collapsed := ne.CollapsedMap[id]; !collapsed || ne.IsLocked(id) {
+		return false
+	}
+	ne.uncollapse(id)
+	return true
+}
+
+// Sets the state of the Node. An uncollapsed Node is marked as collapsed in the next step, a collapsed one keeps its step.
+// Returns false if the Node doesn't exist or is locked.
+func (ne *NodeEnvironment) SetState(id NodeID, state NodeState) bool {
+	if _, exists := ne.NodesMap[id]; !exists || ne.IsLocked(id) {
+		return false
+	}
+	if _, collapsed := ne.CollapsedMap[id]; !collapsed {
+		ne.CollapsedMap[id] = len(ne.CollapsedMap)
+	}
+	ne.StatesMap[id] = state
+	return true
+}
+
+// Locks the state of a collapsed Node, so that it can't be uncollapsed, changed or rerolled until it's unlocked.
+// Returns false if the Node isn't collapsed.
+func (ne *NodeEnvironment) Lock(id NodeID) bool {
+	if _, collapsed := ne.CollapsedMap[id]; !collapsed {
+		return false
+	}
+	if ne.locked == nil {
+		ne.locked = map[NodeID]bool{}
+	}
+	ne.locked[id] = true
+	return true
+}
+
+// Unlocks the Node. Returns false if the Node wasn't locked.
+func (ne *NodeEnvironment) Unlock(id NodeID) bool {
+	if !ne.locked[id] {
+		return false
+	}
+	delete(ne.locked, id)
+	return true
+}
+
+func (ne *NodeEnvironment) IsLocked(id NodeID) bool {
+	return ne.locked[id]
+}
+
+// Returns the IDs of the locked Nodes, in order of their collapse steps.
+func (ne *NodeEnvironment) Locked() NodeIDs {
+	ids := NodeIDs{}
+	for _, id := range ne.Collapsed() {
+		if ne.locked[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Removes the states of the Nodes regardless of locks and renumbers the collapse steps of the remaining Nodes, so that they stay dense.
+func (ne *NodeEnvironment) uncollapse(ids ...NodeID) {
+	removed := false
+	for _, id := range ids {
+		if _, collapsed := ne.CollapsedMap[id]; collapsed {
+			delete(ne.CollapsedMap, id)
+			removed = true
+		}
+		delete(ne.StatesMap, id)
+		delete(ne.locked, id)
+	}
+	if !removed {
+		return
+	}
+	ne.version++
+	steps := make(NodeIDs, 0, len(ne.CollapsedMap))
+	for id := range ne.CollapsedMap {
+		steps = append(steps, id)
+	}
+	sort.Slice(steps, func(i, j int) bool { return ne.CollapsedMap[steps[i]] < ne.CollapsedMap[steps[j]] })
+	for at, id := range steps {
+		ne.CollapsedMap[id] = at
+	}
+}
@@ -0,0 +1,50 @@
+package gwc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_UncollapseAndSetState(t *testing.T) {
+	env := New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, newLinearNodes(newAbcdNodeSuperposition()...)).Collapse()
+
+	assert.True(t, env.Uncollapse("1"))
+	assert.False(t, env.Uncollapse("1"))
+	assert.False(t, env.Uncollapse("unknown"))
+	assert.EqualValues(t, NodeIDs{"0", "2", "3"}, env.Collapsed())
+	assert.Equal(t, 2, env.CollapsedMap["3"])
+	assert.Nil(t, env.StatesMap["1"])
+
+	assert.True(t, env.SetState("1", "X"))
+	assert.True(t, env.SetState("0", "Y"))
+	assert.False(t, env.SetState("unknown", "Z"))
+	assert.EqualValues(t, NodeIDs{"0", "2", "3", "1"}, env.Collapsed())
+	assert.EqualValues(t, NodeStates{"Y", "X"}, env.States()[:2])
+}
+
+func Test_Lock(t *testing.T) {
+	sim := New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, newLinearNodes(newAbcdNodeSuperposition()...))
+	env := sim.Environment()
+	assert.False(t, env.Lock("0"))
+
+	env = sim.Collapse()
+	assert.True(t, env.Lock("2"))
+	assert.True(t, env.Lock("0"))
+	assert.EqualValues(t, NodeIDs{"0", "2"}, env.Locked())
+	assert.False(t, env.Uncollapse("2"))
+	assert.False(t, env.SetState("2", "X"))
+
+	// Rerolling keeps the locked Nodes.
+	state := env.StatesMap["2"]
+	rerolled := sim.Reroll(env, NodeIDs{"1", "2", "3"}, 0)
+	assert.EqualValues(t, NodeIDs{"0", "2", "1", "3"}, rerolled.Collapsed())
+	assert.Equal(t, state, rerolled.StatesMap["2"])
+	assert.True(t, rerolled.IsLocked("2"))
+
+	assert.True(t, env.Unlock("2"))
+	assert.False(t, env.Unlock("2"))
+	assert.True(t, env.Uncollapse("2"))
+	assert.EqualValues(t, NodeIDs{"0"}, env.Locked())
+}
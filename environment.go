@@ -1,25 +1,90 @@
 package gwc
 
-import "fmt"
-
 func NewNodeEnvironment(nodes Nodes) *NodeEnvironment {
+	return newNodeEnvironment(nodes, false)
+}
+
+// Builds a NodeEnvironment, which treats every edge as undirected. Neighbour lists are symmetrised automatically.
+func NewUndirectedNodeEnvironment(nodes Nodes) *NodeEnvironment {
+	return newNodeEnvironment(nodes, true)
+}
+
+func newNodeEnvironment(nodes Nodes, undirected bool) *NodeEnvironment {
+	return newNodeEnvironmentWithEdges(nodes, nil, undirected)
+}
+
+// Builds a NodeEnvironment, whose Nodes have the additional outgoing edges on top of their own neighbours.
+func newNodeEnvironmentWithEdges(nodes Nodes, edges []Edge, undirected bool) *NodeEnvironment {
 	nodes_map := NodesMap{}
 	for _, node := range nodes {
 		nodes_map[node.ID()] = node
 	}
 
+	// The Nodes' own neighbours suffice, unless there are additional edges or the edges are undirected.
+	var adjacency map[NodeID]NodeIDs
+	neighbours := func(node Node) NodeIDs {
+		return node.Neighbours()
+	}
+	if undirected || len(edges) > 0 {
+		adjacency = map[NodeID]NodeIDs{}
+		for _, node := range nodes {
+			adjacency[node.ID()] = append(NodeIDs{}, node.Neighbours()...)
+		}
+		for _, edge := range edges {
+			if _, exists := nodes_map[edge.From]; exists && !adjacency[edge.From].Contains(edge.To) {
+				adjacency[edge.From] = append(adjacency[edge.From], edge.To)
+			}
+		}
+		neighbours = func(node Node) NodeIDs {
+			return adjacency[node.ID()]
+		}
+	}
+
+	// Index the incoming edges of every Node and, in undirected mode, add them to the outgoing ones.
+	predecessors := map[NodeID]NodeIDs{}
+	for _, node := range nodes {
+		for _, ni := range neighbours(node) {
+			if _, exists := nodes_map[ni]; exists && !predecessors[ni].Contains(node.ID()) {
+				predecessors[ni] = append(predecessors[ni], node.ID())
+			}
+		}
+	}
+	if undirected {
+		for _, node := range nodes {
+			id := node.ID()
+			for _, ni := range predecessors[id] {
+				if !adjacency[id].Contains(ni) {
+					adjacency[id] = append(adjacency[id], ni)
+				}
+			}
+		}
+		predecessors = adjacency
+	}
+
 	var current NodeID
 	if len(nodes) > 0 {
 		current = nodes[0].ID()
 	}
 
-	return &NodeEnvironment{
+	env := &NodeEnvironment{
 		Current:      current,
 		Nodes:        nodes,
 		NodesMap:     nodes_map,
 		CollapsedMap: NodeCollapsedMap{},
 		StatesMap:    NodeStatesMap{},
+		Undirected:   undirected,
+		adjacency:    adjacency,
+		predecessors: predecessors,
+		attempts:     map[NodeID]int{},
+		locked:       map[NodeID]bool{},
+
+		EdgeCollapsedMap: EdgeCollapsedMap{},
+		EdgeStatesMap:    EdgeStatesMap{},
+		edgeAttempts:     map[Edge]int{},
+		growth:           &graphChanges{},
 	}
+	env.edges, env.edgeIndex = buildEdgeIndex(nodes, env.Neighbours, nodes_map, undirected)
+	return env
 }
 
 type NodeEnvironment struct {
@@ -28,11 +93,35 @@ type NodeEnvironment struct {
 	Current      NodeID
 	CollapsedMap NodeCollapsedMap
 	StatesMap    NodeStatesMap
+	// Undirected environments treat every edge as traversable in both directions.
+	Undirected bool
+
+	// The Edge being collapsed, or the zero Edge while a Node is being collapsed.
+	CurrentEdge      Edge
+	EdgeCollapsedMap EdgeCollapsedMap
+	EdgeStatesMap    EdgeStatesMap
+
+	adjacency    map[NodeID]NodeIDs
+	predecessors map[NodeID]NodeIDs
+	distances    *distanceIndex
+	attempts     map[NodeID]int
+	locked       map[NodeID]bool
+	edges        []Edge
+	edgeIndex    map[Edge]int
+	edgeAttempts map[Edge]int
+	growth       *graphChanges
+	parent       *NodeEnvironment
+	parentOf     map[NodeID]NodeID
+	orders       *orderRun
+	// version is increased whenever the graph changes or Nodes are uncollapsed, so that stateful CollapseOrderFns start over.
+	version int
 }
 
 type NodeStates = []NodeState
 type NodeStatesMap = map[NodeID]NodeState
 type NodeCollapsedMap = map[NodeID]int
+
+// NodeFilterFn decides whether a Node is kept, based on its ID and its state, which is nil for uncollapsed Nodes.
 type NodeFilterFn = func(NodeID, NodeState) bool
 type NodeIDsOrNodeFilterFn = interface{}
 
@@ -78,11 +167,44 @@ func (ne *NodeEnvironment) CollapsedNodes() Nodes {
 	return nodes
 }
 
+// Returns the attributes of the Node, or nil if the Node has no attributes.
+func (ne *NodeEnvironment) Attributes(id NodeID) NodeAttributes {
+	if node, ok := ne.NodesMap[id].(AttributedNode); ok {
+		return node.Attributes()
+	}
+	return nil
+}
+
+// Returns the IDs of the Nodes reachable via the Node's outgoing edges, which are all of its edges in undirected mode.
+func (ne *NodeEnvironment) Neighbours(id NodeID) NodeIDs {
+	if ne.adjacency != nil {
+		return ne.adjacency[id]
+	}
+	if node, exists := ne.NodesMap[id]; exists {
+		return node.Neighbours()
+	}
+	return nil
+}
+
+// Returns the IDs of the Nodes that list the Node as their neighbour, which equals Neighbours() in undirected mode.
+func (ne *NodeEnvironment) Predecessors(id NodeID) NodeIDs {
+	if ne.predecessors != nil {
+		return ne.predecessors[id]
+	}
+	ids := NodeIDs{}
+	for _, node := range ne.Nodes {
+		if node.Neighbours().Contains(id) {
+			ids = append(ids, node.ID())
+		}
+	}
+	return ids
+}
+
 func (ne *NodeEnvironment) IsNeighbour(other NodeID) bool {
 	return ne.IsNeighbourOf(ne.Current, other)
 }
 func (ne *NodeEnvironment) IsNeighbourOf(id, other NodeID) bool {
-	for _, ni := range ne.NodesMap[id].Neighbours() {
+	for _, ni := range ne.Neighbours(id) {
 		if ni == other {
 			return true
 		}
@@ -94,12 +216,8 @@ func (ne *NodeEnvironment) IsWithinRange(other NodeID, depth uint) bool {
 	return ne.IsWithinRangeOf(ne.Current, other, depth)
 }
 func (ne *NodeEnvironment) IsWithinRangeOf(a, b NodeID, depth uint) bool {
-	for _, ni := range ne.aggregateNodeNeighbours(NodeIDs{}, a, depth) {
-		if ni == b {
-			return true
-		}
-	}
-	return false
+	d, reached := ne.search(a, int(depth)).distances[b]
+	return reached && d <= int(depth)
 }
 
 func (ne *NodeEnvironment) NodesWithinRangeExcl(depth uint) NodeIDs {
@@ -112,37 +230,12 @@ func (ne *NodeEnvironment) NodesWithinRangeOfExcl(id NodeID, depth uint) NodeIDs
 	return ne.NodesWithinRangeOfIncl(id, depth)[1:]
 }
 func (ne *NodeEnvironment) NodesWithinRangeOfIncl(id NodeID, depth uint) NodeIDs {
-	return ne.aggregateNodeNeighbours(NodeIDs{id}, id, depth)
-}
-func (ne *NodeEnvironment) aggregateNodeNeighbours(ids NodeIDs, id NodeID, depth uint) NodeIDs {
-	// Stop if we're looking for a range smaller than the logical minimum.
-	if depth < 1 {
-		return ids
-	}
-
-	// Find all neighbours that aren't in the list yet.
-	neighbours := NodeIDs{}
-Outer:
-	for _, ni := range ne.NodesMap[id].Neighbours() {
-		for _, nc := range ids {
-			if nc == ni {
-				continue Outer
-			}
-		}
-		neighbours = append(neighbours, ni)
-	}
-
-	// Add the found new neighbours to the list of nodes in range.
-	ids = append(ids, neighbours...)
-
-	// Continue aggregation with the neighbours that hadn't been in the list yet.
-	for _, ni := range neighbours {
-		ids = ne.aggregateNodeNeighbours(ids, ni, depth-1)
-	}
-
-	return ids
+	return ne.search(id, int(depth)).within(int(depth))
 }
 
+// Returns the provided IDs, or the IDs of all Nodes accepted by the provided NodeFilterFn. Other types yield no IDs.
+//
+// Deprecated: Use the type-safe Query() instead.
 func (ne *NodeEnvironment) FilterNodes(fn_or_ids NodeIDsOrNodeFilterFn) NodeIDs {
 	switch v := fn_or_ids.(type) {
 	case []NodeID:
@@ -150,31 +243,60 @@ func (ne *NodeEnvironment) FilterNodes(fn_or_ids NodeIDsOrNodeFilterFn) NodeIDs
 	case NodeIDs:
 		return v
 	case NodeFilterFn:
-		filtered := NodeIDs{}
-		for _, node := range ne.Nodes {
-			id := node.ID()
-			if v(id, node) {
-				filtered = append(filtered, id)
-			}
-		}
-		return filtered
+		return ne.Query().Where(v).IDs()
 	}
-
-	panic(fmt.Sprintf("FilterNodes() cannot handle this type: %#v", fn_or_ids))
+	return NodeIDs{}
 }
 
+// Deprecated: Use the type-safe Query() instead.
 func (ne *NodeEnvironment) FilterNodesAnd(fn1 NodeIDsOrNodeFilterFn, fns ...NodeIDsOrNodeFilterFn) NodeIDs {
-	filtered := ne.FilterNodes(fn1)
+	filtered := ne.FilterNodes(fn1).Set()
 	for _, fn := range fns {
-		filtered = filtered.And(ne.FilterNodes(fn))
+		filtered = filtered.Intersect(ne.FilterNodes(fn).Set())
 	}
-	return filtered
+	return filtered.IDs()
 }
 
+// Deprecated: Use the type-safe Query() instead.
 func (ne *NodeEnvironment) FilterNodesOr(fn1 NodeIDsOrNodeFilterFn, fns ...NodeIDsOrNodeFilterFn) NodeIDs {
-	filtered := ne.FilterNodes(fn1)
+	filtered := ne.FilterNodes(fn1).Set()
 	for _, fn := range fns {
-		filtered = filtered.Or(ne.FilterNodes(fn))
+		filtered.Add(ne.FilterNodes(fn)...)
+	}
+	return filtered.IDs()
+}
+
+// Returns a copy of the environment, whose states, collapse steps and graph can be changed without affecting the original.
+func (ne *NodeEnvironment) clone() NodeEnvironment {
+	env := *ne
+	env.CollapsedMap = make(NodeCollapsedMap, len(ne.CollapsedMap))
+	for id, at := range ne.CollapsedMap {
+		env.CollapsedMap[id] = at
+	}
+	env.StatesMap = make(NodeStatesMap, len(ne.StatesMap))
+	for id, state := range ne.StatesMap {
+		env.StatesMap[id] = state
+	}
+	env.attempts = make(map[NodeID]int, len(ne.attempts))
+	for id, n := range ne.attempts {
+		env.attempts[id] = n
+	}
+	env.locked = make(map[NodeID]bool, len(ne.locked))
+	for id := range ne.locked {
+		env.locked[id] = true
+	}
+	env.EdgeCollapsedMap = make(EdgeCollapsedMap, len(ne.EdgeCollapsedMap))
+	for edge, at := range ne.EdgeCollapsedMap {
+		env.EdgeCollapsedMap[edge] = at
+	}
+	env.EdgeStatesMap = make(EdgeStatesMap, len(ne.EdgeStatesMap))
+	for edge, state := range ne.EdgeStatesMap {
+		env.EdgeStatesMap[edge] = state
+	}
+	env.edgeAttempts = make(map[Edge]int, len(ne.edgeAttempts))
+	for edge, n := range ne.edgeAttempts {
+		env.edgeAttempts[edge] = n
 	}
-	return filtered
+	env.growth = &graphChanges{}
+	return env
 }
@@ -157,10 +157,17 @@ func Test_FilterNodes(t *testing.T) {
 	assert.EqualValues(t, NodeIDs{"4", "5", "6"}, filtered_indexes)
 	assert.EqualValues(t, NodeIDs{"0", "1", "2"}, filtered)
 
-	assert.Panics(t, func() {
-		ne.FilterNodes("invalid")
+	assert.NotPanics(t, func() {
+		assert.Empty(t, ne.FilterNodes("invalid"))
 	})
 
+	// The filter function receives the state of the Node.
+	ne.StatesMap["3"] = "water"
+	in_state := ne.FilterNodes(func(_ NodeID, s NodeState) bool {
+		return s == "water"
+	})
+	assert.EqualValues(t, NodeIDs{"3"}, in_state)
+
 	as := ne.FilterNodesAnd(filtered_indexes_slice, filtered)
 	bs := ne.FilterNodesOr(filtered_indexes, filtered)
 	cs := ne.FilterNodesAnd(filtered, filtered)
@@ -179,3 +186,46 @@ func Test_NewNodeEnvironment(t *testing.T) {
 	assert.NotNil(t, ne_empty)
 	assert.NotNil(t, ne_filled)
 }
+
+func Test_UnknownNeighbours(t *testing.T) {
+	ne := NewNodeEnvironment(Nodes{NewNode("a", nil, "b", "x")})
+
+	assert.False(t, ne.IsNeighbourOf("x", "a"))
+	assert.False(t, ne.IsWithinRangeOf("a", "y", 3))
+	assert.EqualValues(t, NodeIDs{"a", "b", "x"}, ne.NodesWithinRangeOfIncl("a", 2))
+}
+
+func Test_UndirectedNodeEnvironment(t *testing.T) {
+	// The graph has the following form, with one-way edges:
+	// a -> b -> c
+	// ^         |
+	// +---------+
+	nodes := Nodes{
+		NewNode("a", nil, "b"),
+		NewNode("b", nil, "c"),
+		NewNode("c", nil, "a"),
+		NewNode("d", nil, "c"),
+	}
+
+	directed := NewNodeEnvironment(nodes)
+	assert.False(t, directed.Undirected)
+	assert.EqualValues(t, NodeIDs{"c"}, directed.Neighbours("b"))
+	assert.EqualValues(t, NodeIDs{"a"}, directed.Predecessors("b"))
+	assert.EqualValues(t, NodeIDs{"b", "d"}, directed.Predecessors("c"))
+	assert.Empty(t, directed.Predecessors("d"))
+	assert.Nil(t, directed.Neighbours("x"))
+	assert.False(t, directed.IsNeighbourOf("b", "a"))
+	assert.False(t, directed.IsWithinRangeOf("c", "d", 3))
+
+	undirected := NewUndirectedNodeEnvironment(nodes)
+	assert.True(t, undirected.Undirected)
+	assert.EqualValues(t, NodeIDs{"c", "a"}, undirected.Neighbours("b"))
+	assert.EqualValues(t, NodeIDs{"a", "b", "d"}, undirected.Neighbours("c"))
+	assert.EqualValues(t, undirected.Neighbours("c"), undirected.Predecessors("c"))
+	assert.True(t, undirected.IsNeighbourOf("b", "a"))
+	assert.True(t, undirected.IsWithinRangeOf("a", "d", 2))
+
+	// Environments built without constructor fall back to scanning the Nodes.
+	literal := NodeEnvironment{Nodes: nodes, NodesMap: directed.NodesMap}
+	assert.EqualValues(t, NodeIDs{"b", "d"}, literal.Predecessors("c"))
+}
@@ -0,0 +1,183 @@
+package gwc
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Colours used by ExportDOT for the states, in order of their first appearance.
+var DOTPalette = []string{
+	"#8dd3c7", "#ffffb3", "#bebada", "#fb8072", "#80b1d3", "#fdb462",
+	"#b3de69", "#fccde5", "#d9d9d9", "#bc80bd", "#ccebc5", "#ffed6f",
+}
+
+// Formats a state for the text based exports. A nil state is formatted as empty string.
+func FormatState(state NodeState) string {
+	if state == nil {
+		return ""
+	}
+	return fmt.Sprint(state)
+}
+
+// Returns whether every edge of the environment's graph has a counterpart in the opposite direction.
+func isSymmetric(env NodeEnvironment) bool {
+	for _, node := range env.Nodes {
+		for _, ni := range env.Neighbours(node.ID()) {
+			if _, exists := env.NodesMap[ni]; !exists || !env.IsNeighbourOf(ni, node.ID()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns the edges of the environment's graph. Edges of symmetric graphs are only returned once.
+func exportEdges(env NodeEnvironment) ([][2]NodeID, bool) {
+	directed := !isSymmetric(env)
+	indexes := make(map[NodeID]int, len(env.Nodes))
+	for idx, node := range env.Nodes {
+		indexes[node.ID()] = idx
+	}
+
+	edges := [][2]NodeID{}
+	for idx, node := range env.Nodes {
+		for _, ni := range env.Neighbours(node.ID()) {
+			if !directed && indexes[ni] < idx {
+				continue
+			}
+			edges = append(edges, [2]NodeID{node.ID(), ni})
+		}
+	}
+	return edges, directed
+}
+
+// Writes the environment as Graphviz DOT graph. Nodes are labeled with their state and collapse step and coloured by state.
+// If color is nil, the colours are taken from DOTPalette.
+func ExportDOT(w io.Writer, env NodeEnvironment, color func(NodeState) string) error {
+	if color == nil {
+		colors := map[string]string{}
+		color = func(state NodeState) string {
+			key := FormatState(state)
+			if _, exists := colors[key]; !exists {
+				colors[key] = DOTPalette[len(colors)%len(DOTPalette)]
+			}
+			return colors[key]
+		}
+	}
+
+	edges, directed := exportEdges(env)
+	kind, op := "graph", "--"
+	if directed {
+		kind, op = "digraph", "->"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(kind + " {\n")
+	sb.WriteString("\tnode [style=filled];\n")
+	for _, node := range env.Nodes {
+		id := node.ID()
+		label := id
+		attrs := ""
+		if step, collapsed := env.CollapsedMap[id]; collapsed {
+			state := env.StatesMap[id]
+			label += "\n" + FormatState(state) + "\n#" + strconv.Itoa(step)
+			attrs = fmt.Sprintf(", state=%s, step=%d, fillcolor=%s", strconv.Quote(FormatState(state)), step, strconv.Quote(color(state)))
+		} else {
+			attrs = ", fillcolor=\"white\""
+		}
+		fmt.Fprintf(&sb, "\t%s [label=%s%s];\n", strconv.Quote(id), strconv.Quote(label), attrs)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&sb, "\t%s %s %s;\n", strconv.Quote(e[0]), op, strconv.Quote(e[1]))
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type exportJSONNode struct {
+	ID         NodeID         `json:"id"`
+	State      NodeState      `json:"state"`
+	Step       *int           `json:"step"`
+	Attributes NodeAttributes `json:"attributes,omitempty"`
+}
+
+type exportJSONEdge struct {
+	Source NodeID `json:"source"`
+	Target NodeID `json:"target"`
+}
+
+// Writes the environment as JSON, using the same schema as ImportJSON. Nodes additionally contain their state and collapse step,
+// which are null for uncollapsed Nodes. States need to be encodable by encoding/json.
+func ExportJSON(w io.Writer, env NodeEnvironment) error {
+	edges, directed := exportEdges(env)
+
+	graph := struct {
+		Directed bool             `json:"directed"`
+		Nodes    []exportJSONNode `json:"nodes"`
+		Edges    []exportJSONEdge `json:"edges"`
+	}{
+		Directed: directed,
+		Nodes:    make([]exportJSONNode, len(env.Nodes)),
+		Edges:    make([]exportJSONEdge, len(edges)),
+	}
+	for i, node := range env.Nodes {
+		id := node.ID()
+		graph.Nodes[i] = exportJSONNode{ID: id, Attributes: env.Attributes(id)}
+		if step, collapsed := env.CollapsedMap[id]; collapsed {
+			graph.Nodes[i].State = env.StatesMap[id]
+			graph.Nodes[i].Step = &step
+		}
+	}
+	for i, e := range edges {
+		graph.Edges[i] = exportJSONEdge{e[0], e[1]}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(graph)
+}
+
+// Writes the environment as CSV with one row per Node, containing its ID, state, collapse step,
+// space separated neighbours and attributes. Uncollapsed Nodes have an empty state and step.
+func ExportCSV(w io.Writer, env NodeEnvironment) error {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, node := range env.Nodes {
+		for name := range env.Attributes(node.ID()) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"id", "state", "step", "neighbours"}, names...)); err != nil {
+		return err
+	}
+	for _, node := range env.Nodes {
+		id := node.ID()
+		state, step := "", ""
+		if at, collapsed := env.CollapsedMap[id]; collapsed {
+			state, step = FormatState(env.StatesMap[id]), strconv.Itoa(at)
+		}
+		row := []string{id, state, step, strings.Join(env.Neighbours(id), " ")}
+		attrs := env.Attributes(id)
+		for _, name := range names {
+			row = append(row, attrs[name])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
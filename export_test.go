@@ -0,0 +1,86 @@
+package gwc
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newExportTestEnvironment() NodeEnvironment {
+	nodes := newLinearNodes(newAbcdNodeSuperposition()...)
+	env := New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, nodes[:3]).Collapse()
+	return env
+}
+
+func Test_ExportDOT(t *testing.T) {
+	env := newExportTestEnvironment()
+	delete(env.CollapsedMap, "2")
+
+	var buf bytes.Buffer
+	assert.NoError(t, ExportDOT(&buf, env, nil))
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "digraph {\n"))
+	assert.Contains(t, out, "\t\"0\" [label=\"0\\nA\\n#0\", state=\"A\", step=0, fillcolor=\"#8dd3c7\"];\n")
+	assert.Contains(t, out, "\t\"2\" [label=\"2\", fillcolor=\"white\"];\n")
+	assert.Contains(t, out, "\t\"2\" -> \"3\";\n")
+
+	// Symmetric graphs are written as undirected graphs and each edge is only written once.
+	env = *NewNodeEnvironment(newLinearNodes())
+	buf.Reset()
+	assert.NoError(t, ExportDOT(&buf, env, func(NodeState) string { return "red" }))
+	assert.True(t, strings.HasPrefix(buf.String(), "graph {\n"))
+	assert.Equal(t, 3, strings.Count(buf.String(), " -- "))
+}
+
+func Test_ExportJSON(t *testing.T) {
+	env := newExportTestEnvironment()
+	env.Nodes[2] = NewNode("2", nil, "1")
+	env.NodesMap["2"] = env.Nodes[2]
+	delete(env.CollapsedMap, "2")
+
+	var buf bytes.Buffer
+	assert.NoError(t, ExportJSON(&buf, env))
+	assert.JSONEq(t, `{
+		"directed": false,
+		"nodes": [
+			{"id": "0", "state": "A", "step": 0},
+			{"id": "1", "state": "A", "step": 1},
+			{"id": "2", "state": null, "step": null}
+		],
+		"edges": [
+			{"source": "0", "target": "1"},
+			{"source": "1", "target": "2"}
+		]
+	}`, buf.String())
+
+	// The exported JSON can be imported again.
+	nodes, err := ImportJSON(&buf, ImportOptions{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[NodeID]NodeIDs{
+		"0": {"1"},
+		"1": {"0", "2"},
+		"2": {"1"},
+	}, neighboursOf(nodes))
+}
+
+func Test_ExportCSV(t *testing.T) {
+	nodes, err := ImportJSON(strings.NewReader(`{
+		"nodes": [
+			{"id": "a", "attributes": {"biome": "forest"}, "neighbours": ["b"]},
+			{"id": "b", "attributes": {"height": "3"}}
+		]
+	}`), ImportOptions{})
+	assert.NoError(t, err)
+
+	env := *NewNodeEnvironment(nodes)
+	env.StatesMap["b"] = 42
+	env.CollapsedMap["b"] = 0
+
+	var buf bytes.Buffer
+	assert.NoError(t, ExportCSV(&buf, env))
+	assert.Equal(t, "id,state,step,neighbours,biome,height\na,,,b,forest,\nb,42,0,a,,3\n", buf.String())
+}
@@ -0,0 +1,266 @@
+package gwc
+
+import "strconv"
+
+// PatternNode matches a single Node of a NodeEnvironment.
+type PatternNode struct {
+	// Local ID of the pattern node, which is used by the pattern's edges and the productions.
+	ID NodeID
+	// State the Node has to be collapsed into. Nil matches any Node, including uncollapsed ones.
+	State NodeState
+	// Optional additional condition the Node has to fulfil.
+	Where func(env NodeEnvironment, id NodeID) bool
+}
+
+// Pattern is a subgraph made of PatternNodes and the edges between them, using their local IDs.
+// In directed environments each edge has to exist in its direction, in undirected environments in either direction.
+type Pattern struct {
+	Nodes []PatternNode
+	Edges []Edge
+}
+
+// Match maps the local IDs of a Pattern's nodes to the IDs of the matched Nodes.
+type Match map[NodeID]NodeID
+
+// ProductionNode is a single Node of a Production's replacement.
+type ProductionNode struct {
+	// Local ID of the node. If the pattern has a node with the same ID, the matched Node is kept, otherwise a new Node is added.
+	ID NodeID
+	// State the Node is set to. Nil leaves kept Nodes as they are and new Nodes uncollapsed.
+	State NodeState
+	// Builds new Nodes with the provided ID, e.g. with a superposition for collapsing them later on.
+	// New Nodes get their edges from the Production, so their own neighbours should be empty. Nil builds Nodes without state function.
+	New func(id NodeID) Node
+}
+
+// Production replaces the matched subgraph: matched Nodes and edges missing from the Production are removed, all others are added.
+// Edges between matched Nodes and Nodes outside the pattern are kept, as long as the matched Node is kept.
+type Production struct {
+	Weight NodeProbability
+	Nodes  []ProductionNode
+	Edges  []Edge
+}
+
+// Rule rewrites matches of its Pattern with one of its Productions, chosen at random according to their weights.
+type Rule struct {
+	Name        string
+	Pattern     Pattern
+	Productions []Production
+}
+
+// Grammar is a set of Rules, which rewrite a NodeEnvironment's graph, e.g. to generate missions and spaces as described by Dormans.
+// Rules can be applied to uncollapsed environments before collapsing them, as well as to collapsed ones or in between,
+// e.g. from a CollapseHookFn. They shouldn't be applied from within state functions, as changes of the graph are deferred there.
+type Grammar struct {
+	Rules []Rule
+}
+
+// Returns all matches of the pattern, ordered by the Nodes of the environment matched by the first pattern node, and so on.
+func (p *Pattern) Matches(env NodeEnvironment) []Match {
+	matches := []Match{}
+	if len(p.Nodes) == 0 {
+		return matches
+	}
+	p.match(&env, Match{}, NewNodeIDSet(), 0, &matches)
+	return matches
+}
+
+func (p *Pattern) match(env *NodeEnvironment, match Match, used *NodeIDSet, pos int, matches *[]Match) {
+	if pos == len(p.Nodes) {
+		found := make(Match, len(match))
+		for local, id := range match {
+			found[local] = id
+		}
+		*matches = append(*matches, found)
+		return
+	}
+
+	pn := p.Nodes[pos]
+	for _, id := range p.candidates(env, match, pn.ID) {
+		if used.Contains(id) || !p.accepts(env, match, pn, id) {
+			continue
+		}
+		match[pn.ID] = id
+		used.Add(id)
+		p.match(env, match, used, pos+1, matches)
+		used.Remove(id)
+		delete(match, pn.ID)
+	}
+}
+
+// Returns the Nodes a pattern node may be matched to, which are limited by the edges to already matched pattern nodes.
+func (p *Pattern) candidates(env *NodeEnvironment, match Match, local NodeID) NodeIDs {
+	for _, edge := range p.Edges {
+		if id, matched := match[edge.From]; matched && edge.To == local {
+			return env.Neighbours(id)
+		}
+		if id, matched := match[edge.To]; matched && edge.From == local {
+			return env.Predecessors(id)
+		}
+	}
+	ids := make(NodeIDs, len(env.Nodes))
+	for idx, node := range env.Nodes {
+		ids[idx] = node.ID()
+	}
+	return ids
+}
+
+func (p *Pattern) accepts(env *NodeEnvironment, match Match, pn PatternNode, id NodeID) bool {
+	if _, exists := env.NodesMap[id]; !exists {
+		return false
+	}
+	if pn.State != nil {
+		if _, collapsed := env.CollapsedMap[id]; !collapsed || !StatesEqual(env.StatesMap[id], pn.State) {
+			return false
+		}
+	}
+	if pn.Where != nil && !pn.Where(*env, id) {
+		return false
+	}
+	for _, edge := range p.Edges {
+		from, to := match[edge.From], match[edge.To]
+		if edge.From == pn.ID {
+			from = id
+		} else if edge.To == pn.ID {
+			to = id
+		} else {
+			continue
+		}
+		if from != "" && to != "" && !env.IsNeighbourOf(from, to) {
+			return false
+		}
+	}
+	return true
+}
+
+// Rewrites a random match of the Rule's pattern with a random Production. Returns false if the pattern doesn't match.
+func (r *Rule) Apply(rnd Random, env *NodeEnvironment) bool {
+	matches := r.Pattern.Matches(*env)
+	if len(matches) == 0 || len(r.Productions) == 0 {
+		return false
+	}
+	r.Rewrite(env, matches[rnd.Intn(len(matches))], r.choose(rnd))
+	return true
+}
+
+// Chooses a Production according to the weights, or a random one if no Production has a positive weight.
+func (r *Rule) choose(rnd Random) Production {
+	sum := NodeProbability(0)
+	for _, production := range r.Productions {
+		sum += production.Weight
+	}
+	if sum <= 0 {
+		return r.Productions[rnd.Intn(len(r.Productions))]
+	}
+	compare := rnd.Float64() * sum
+	for _, production := range r.Productions {
+		compare -= production.Weight
+		if compare < 0 {
+			return production
+		}
+	}
+	return r.Productions[len(r.Productions)-1]
+}
+
+// Replaces the match of the Rule's pattern with the Production.
+func (r *Rule) Rewrite(env *NodeEnvironment, match Match, production Production) {
+	kept := NewNodeIDSet()
+	for _, pn := range production.Nodes {
+		if _, matched := match[pn.ID]; matched {
+			kept.Add(pn.ID)
+		}
+	}
+	produced := map[Edge]bool{}
+	for _, edge := range production.Edges {
+		produced[edge] = true
+	}
+
+	for _, edge := range r.Pattern.Edges {
+		if kept.Contains(edge.From) && kept.Contains(edge.To) && !produced[edge] {
+			env.RemoveEdge(match[edge.From], match[edge.To])
+		}
+	}
+	for _, pn := range r.Pattern.Nodes {
+		if !kept.Contains(pn.ID) {
+			env.RemoveNode(match[pn.ID])
+		}
+	}
+
+	ids := map[NodeID]NodeID{}
+	for local, id := range match {
+		ids[local] = id
+	}
+	for _, pn := range production.Nodes {
+		if kept.Contains(pn.ID) {
+			continue
+		}
+		id := newNodeID(env, pn.ID)
+		ids[pn.ID] = id
+		if pn.New != nil {
+			env.AddNode(pn.New(id))
+		} else {
+			env.AddNode(NewNode(id, nil))
+		}
+	}
+	for _, edge := range production.Edges {
+		env.AddEdge(ids[edge.From], ids[edge.To])
+	}
+	for _, pn := range production.Nodes {
+		if pn.State != nil {
+			env.SetState(ids[pn.ID], pn.State)
+		}
+	}
+}
+
+// Returns an ID based on the local ID, which isn't used by any Node of the environment yet.
+func newNodeID(env *NodeEnvironment, local NodeID) NodeID {
+	for n := len(env.Nodes); ; n++ {
+		id := local + "#" + strconv.Itoa(n)
+		if _, exists := env.NodesMap[id]; !exists {
+			return id
+		}
+	}
+}
+
+// Returns the Rule with the given name, or nil if there is none.
+func (g *Grammar) Rule(name string) *Rule {
+	for idx := range g.Rules {
+		if g.Rules[idx].Name == name {
+			return &g.Rules[idx]
+		}
+	}
+	return nil
+}
+
+// Applies the Rule with the given name up to n times. Returns the number of times it has been applied.
+func (g *Grammar) ApplyRule(rnd Random, env *NodeEnvironment, name string, n int) int {
+	rule := g.Rule(name)
+	if rule == nil {
+		return 0
+	}
+	for applied := 0; applied < n; applied++ {
+		if !rule.Apply(rnd, env) {
+			return applied
+		}
+	}
+	return n
+}
+
+// Repeatedly applies a random matching Rule until no Rule matches anymore or maxSteps Rules have been applied.
+// A negative maxSteps doesn't limit the number of steps. Returns the number of applied Rules.
+func (g *Grammar) Rewrite(rnd Random, env *NodeEnvironment, maxSteps int) int {
+	steps := 0
+	for ; maxSteps < 0 || steps < maxSteps; steps++ {
+		applied := false
+		for _, idx := range rnd.Perm(len(g.Rules)) {
+			if g.Rules[idx].Apply(rnd, env) {
+				applied = true
+				break
+			}
+		}
+		if !applied {
+			break
+		}
+	}
+	return steps
+}
@@ -0,0 +1,112 @@
+package gwc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PatternMatches(t *testing.T) {
+	env := *NewNodeEnvironment(newDefaultTestNodes())
+	env.SetState("2", "hub")
+	env.SetState("4", "hub")
+
+	// Two connected hubs.
+	hubs := Pattern{
+		Nodes: []PatternNode{{ID: "a", State: "hub"}, {ID: "b", State: "hub"}},
+		Edges: []Edge{{"a", "b"}},
+	}
+	assert.Equal(t, []Match{{"a": "2", "b": "4"}, {"a": "4", "b": "2"}}, hubs.Matches(env))
+
+	// A hub with a leaf, i.e. a neighbour without further neighbours.
+	leaf := Pattern{
+		Nodes: []PatternNode{
+			{ID: "hub", State: "hub"},
+			{ID: "leaf", Where: func(env NodeEnvironment, id NodeID) bool { return len(env.Neighbours(id)) == 1 }},
+		},
+		Edges: []Edge{{"hub", "leaf"}},
+	}
+	assert.Equal(t, []Match{{"hub": "2", "leaf": "0"}, {"hub": "2", "leaf": "1"}, {"hub": "4", "leaf": "6"}}, leaf.Matches(env))
+
+	// Triangles don't exist within the graph.
+	triangle := Pattern{
+		Nodes: []PatternNode{{ID: "a"}, {ID: "b"}, {ID: "c"}},
+		Edges: []Edge{{"a", "b"}, {"b", "c"}, {"c", "a"}},
+	}
+	assert.Empty(t, triangle.Matches(env))
+	assert.Empty(t, (&Pattern{}).Matches(env))
+}
+
+func Test_GrammarRewrite(t *testing.T) {
+	// Mission graph in the style of Dormans: the start expands into a chain of tasks, which ends in a goal.
+	task := func(id NodeID) Node {
+		return NewSuperpositionNode(id, NodeSuperposition{
+			func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) { return 1, "fight" },
+			func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) { return 1, "puzzle" },
+		})
+	}
+	grammar := Grammar{Rules: []Rule{
+		{
+			Name:    "start",
+			Pattern: Pattern{Nodes: []PatternNode{{ID: "s", State: "start"}}},
+			Productions: []Production{{
+				Weight: 1,
+				Nodes:  []ProductionNode{{ID: "s", State: "entrance"}, {ID: "t", State: "tasks"}, {ID: "g", State: "goal"}},
+				Edges:  []Edge{{"s", "t"}, {"t", "g"}},
+			}},
+		},
+		{
+			Name:    "tasks",
+			Pattern: Pattern{Nodes: []PatternNode{{ID: "a"}, {ID: "t", State: "tasks"}, {ID: "g"}}, Edges: []Edge{{"a", "t"}, {"t", "g"}}},
+			Productions: []Production{
+				{Weight: 3, Nodes: []ProductionNode{{ID: "a"}, {ID: "x", New: task}, {ID: "t"}, {ID: "g"}}, Edges: []Edge{{"a", "x"}, {"x", "t"}, {"t", "g"}}},
+				{Weight: 1, Nodes: []ProductionNode{{ID: "a"}, {ID: "x", New: task}, {ID: "g"}}, Edges: []Edge{{"a", "x"}, {"x", "g"}}},
+			},
+		},
+	}}
+	assert.Nil(t, grammar.Rule("unknown"))
+	assert.Equal(t, 0, grammar.ApplyRule(nil, nil, "unknown", 1))
+
+	rnd := rand.New(rand.NewSource(42))
+	sim := New(rnd, AscendingCollapseOrder, Nodes{NewNode("start", nil)})
+	env := sim.Environment()
+	env.SetState("start", "start")
+	assert.Equal(t, 1, grammar.ApplyRule(rnd, &env, "start", 5))
+	assert.EqualValues(t, NodeIDs{"start", "t#1", "g#2"}, env.Collapsed())
+
+	steps := grammar.Rewrite(rnd, &env, -1)
+	assert.True(t, steps >= 1)
+	assert.Empty(t, env.Query().InState("tasks").IDs())
+	assert.Len(t, env.Nodes, 2+steps)
+
+	// The new tasks are collapsed afterwards, and form a chain from the entrance to the goal.
+	env = sim.CollapseSubset(env, env.Query().Uncollapsed().IDs())
+	assert.Len(t, env.Collapsed(), len(env.Nodes))
+	path := env.ShortestPath("start", "g#2")
+	assert.Len(t, path, len(env.Nodes))
+	for _, id := range path[1 : len(path)-1] {
+		assert.Contains(t, []NodeState{"fight", "puzzle"}, env.StatesMap[id])
+	}
+}
+
+func Test_RuleRewriteRemoves(t *testing.T) {
+	env := *NewUndirectedNodeEnvironment(newLinearNodes())
+	for _, id := range (NodeIDs{"0", "1", "2", "3"}) {
+		env.SetState(id, "room")
+	}
+
+	// Merges two connected rooms into one.
+	merge := Rule{
+		Pattern:     Pattern{Nodes: []PatternNode{{ID: "a", State: "room"}, {ID: "b", State: "room"}}, Edges: []Edge{{"a", "b"}}},
+		Productions: []Production{{Nodes: []ProductionNode{{ID: "a", State: "hall"}}}},
+	}
+	merge.Rewrite(&env, Match{"a": "1", "b": "2"}, merge.Productions[0])
+	assert.Len(t, env.Nodes, 3)
+	assert.EqualValues(t, NodeIDs{"0"}, env.Neighbours("1"))
+	assert.Equal(t, "hall", env.StatesMap["1"])
+	assert.EqualValues(t, NodeIDs{"0", "1", "3"}, env.Collapsed())
+
+	// The remaining rooms aren't connected anymore.
+	assert.False(t, merge.Apply(rand.New(rand.NewSource(42)), &env))
+}
@@ -0,0 +1,306 @@
+package gwc
+
+// graphChanges collects the changes of the graph requested while a Node or Edge collapses, which are applied right after it has collapsed.
+// It's shared by all copies of an environment, so that state functions can request changes on the copy they receive.
+type graphChanges struct {
+	deferred bool
+	pending  []func(*NodeEnvironment)
+
+	// owner is the copy of the environment that has copied the graph structure for itself, and may therefore change it in place.
+	// All other copies, which share this struct, copy the structure again before changing it.
+	owner *NodeEnvironment
+	// batches counts the nested batches of changes; the environment's version is increased once the outermost batch ends.
+	batches int
+	changed bool
+	// dangling lists the Nodes with neighbours that don't exist yet, by the missing neighbour's ID.
+	dangling map[NodeID]NodeIDs
+}
+
+// Adds the Node to the environment. Its neighbours may include Nodes that are only added later on.
+// Nodes with an ID that already exists are ignored.
+//
+// Changes requested while a Node or Edge collapses, e.g. from within a NodeStateFn, are applied right after it has collapsed.
+// All other changes are applied immediately, and changes made by a CollapseHookFn count as a single change of the graph.
+// Either way, CollapseOrderFns pick up the changed graph on their next call.
+func (ne *NodeEnvironment) AddNode(node Node) {
+	ne.change(func(env *NodeEnvironment) {
+		env.addNode(node)
+	})
+}
+
+// Adds an edge between the Nodes, which is traversable in both directions in undirected mode. See AddNode() for when it's applied.
+func (ne *NodeEnvironment) AddEdge(from, to NodeID) {
+	ne.change(func(env *NodeEnvironment) {
+		env.addEdge(from, to)
+	})
+}
+
+// Removes the Node, its state and all of its edges from the environment. See AddNode() for when it's applied.
+func (ne *NodeEnvironment) RemoveNode(id NodeID) {
+	ne.change(func(env *NodeEnvironment) {
+		env.removeNode(id)
+	})
+}
+
+// Removes the edge between the Nodes and its state, in both directions in undirected mode. See AddNode() for when it's applied.
+func (ne *NodeEnvironment) RemoveEdge(from, to NodeID) {
+	ne.change(func(env *NodeEnvironment) {
+		env.removeEdge(from, to)
+	})
+}
+
+func (ne *NodeEnvironment) change(fn func(*NodeEnvironment)) {
+	if ne.growth != nil && ne.growth.deferred {
+		ne.growth.pending = append(ne.growth.pending, fn)
+		return
+	}
+	ne.beginChanges()
+	fn(ne)
+	ne.endChanges()
+}
+
+// Starts or stops deferring changes. Deferred changes are applied once deferring stops.
+func (ne *NodeEnvironment) deferChanges(deferred bool) {
+	if ne.growth == nil {
+		return
+	}
+	ne.growth.deferred = deferred
+	if deferred || len(ne.growth.pending) == 0 {
+		return
+	}
+	pending := ne.growth.pending
+	ne.growth.pending = nil
+	ne.beginChanges()
+	for _, fn := range pending {
+		fn(ne)
+	}
+	ne.endChanges()
+}
+
+// Starts a batch of changes, which are applied immediately, but only count as a single change of the graph once the batch ends.
+// Batches may be nested; the outermost one decides when the version of the environment is increased.
+func (ne *NodeEnvironment) beginChanges() {
+	if ne.growth == nil {
+		ne.growth = &graphChanges{}
+	}
+	ne.growth.batches++
+	ne.own()
+}
+
+// Ends a batch of changes. See beginChanges().
+func (ne *NodeEnvironment) endChanges() {
+	ne.growth.batches--
+	if ne.growth.batches > 0 || !ne.growth.changed {
+		return
+	}
+	ne.growth.changed = false
+	ne.version++
+}
+
+// Copies the graph structure before this copy of the environment changes it for the first time, as it may be shared with
+// other environments, e.g. other copies of it or the one a rerolled environment was cloned from. From now on, the adjacency is the only source of the Nodes' neighbours.
+func (ne *NodeEnvironment) own() {
+	if ne.growth.owner == ne {
+		return
+	}
+	ne.growth.owner = ne
+
+	edges := ne.Edges()
+	ne.edges = append([]Edge{}, edges...)
+	edge_index := make(map[Edge]int, len(ne.edgeIndex))
+	for edge, idx := range ne.edgeIndex {
+		edge_index[edge] = idx
+	}
+	ne.edgeIndex = edge_index
+
+	ne.Nodes = append(Nodes{}, ne.Nodes...)
+	nodes_map := make(NodesMap, len(ne.NodesMap))
+	for id, node := range ne.NodesMap {
+		nodes_map[id] = node
+	}
+	ne.NodesMap = nodes_map
+
+	adjacency := make(map[NodeID]NodeIDs, len(ne.Nodes))
+	predecessors := make(map[NodeID]NodeIDs, len(ne.Nodes))
+	dangling := map[NodeID]NodeIDs{}
+	for _, node := range ne.Nodes {
+		id := node.ID()
+		adjacency[id] = ne.Neighbours(id)
+		if !ne.Undirected {
+			predecessors[id] = ne.Predecessors(id)
+		}
+		for _, ni := range adjacency[id] {
+			if _, exists := ne.NodesMap[ni]; !exists {
+				dangling[ni] = append(dangling[ni], id)
+			}
+		}
+	}
+	ne.adjacency = adjacency
+	ne.predecessors = predecessors
+	if ne.Undirected {
+		ne.predecessors = adjacency
+	}
+	ne.growth.dangling = dangling
+}
+
+// Marks the graph as changed. Cached distances are dropped right away, so that they don't outlive the change within a batch.
+func (ne *NodeEnvironment) touch() {
+	ne.growth.changed = true
+	if ne.distances != nil {
+		ne.EnableDistanceCache()
+	}
+}
+
+func (ne *NodeEnvironment) addNode(node Node) {
+	if node == nil {
+		return
+	}
+	id := node.ID()
+	if _, exists := ne.NodesMap[id]; exists {
+		return
+	}
+	ne.touch()
+	ne.Nodes = append(ne.Nodes, node)
+	ne.NodesMap[id] = node
+	ne.adjacency[id] = append(NodeIDs{}, node.Neighbours()...)
+	for _, ni := range ne.adjacency[id] {
+		if _, exists := ne.NodesMap[ni]; exists {
+			ne.link(id, ni)
+		} else {
+			ne.growth.dangling[ni] = append(ne.growth.dangling[ni], id)
+		}
+	}
+
+	// Connect the Nodes that have been waiting for this one.
+	for _, from := range ne.growth.dangling[id] {
+		if _, exists := ne.NodesMap[from]; exists && ne.adjacency[from].Contains(id) {
+			ne.link(from, id)
+		}
+	}
+	delete(ne.growth.dangling, id)
+}
+
+func (ne *NodeEnvironment) addEdge(from, to NodeID) {
+	if _, exists := ne.NodesMap[from]; !exists || ne.adjacency[from].Contains(to) {
+		return
+	}
+	ne.touch()
+	ne.adjacency[from] = append(append(NodeIDs{}, ne.adjacency[from]...), to)
+	if _, exists := ne.NodesMap[to]; exists {
+		ne.link(from, to)
+	} else {
+		ne.growth.dangling[to] = append(ne.growth.dangling[to], from)
+	}
+}
+
+func (ne *NodeEnvironment) removeNode(id NodeID) {
+	if _, exists := ne.NodesMap[id]; !exists {
+		return
+	}
+	ne.touch()
+	ne.uncollapse(id)
+	incident := []Edge{}
+	for _, ni := range ne.adjacency[id] {
+		if edge, exists := ne.edgeKey(Edge{id, ni}); exists {
+			incident = append(incident, edge)
+		}
+	}
+	for _, ni := range ne.predecessors[id] {
+		if edge, exists := ne.edgeKey(Edge{ni, id}); exists {
+			incident = append(incident, edge)
+		}
+	}
+	ne.uncollapseEdges(incident...)
+	ne.unindexEdges(incident...)
+
+	for _, ni := range ne.adjacency[id] {
+		if ni != id {
+			ne.predecessors[ni] = withoutID(ne.predecessors[ni], id)
+		}
+	}
+	for _, ni := range ne.predecessors[id] {
+		if ni != id {
+			ne.adjacency[ni] = withoutID(ne.adjacency[ni], id)
+		}
+	}
+	delete(ne.adjacency, id)
+	delete(ne.predecessors, id)
+
+	nodes := make(Nodes, 0, len(ne.Nodes)-1)
+	for _, node := range ne.Nodes {
+		if node.ID() != id {
+			nodes = append(nodes, node)
+		}
+	}
+	ne.Nodes = nodes
+	delete(ne.NodesMap, id)
+}
+
+func (ne *NodeEnvironment) removeEdge(from, to NodeID) {
+	if _, exists := ne.NodesMap[from]; !exists {
+		return
+	}
+	ne.touch()
+	edge, _ := ne.edgeKey(Edge{from, to})
+	ne.uncollapseEdges(edge)
+	ne.unindexEdges(edge)
+	ne.adjacency[from] = withoutID(ne.adjacency[from], to)
+	if _, exists := ne.NodesMap[to]; exists {
+		ne.predecessors[to] = withoutID(ne.predecessors[to], from)
+	}
+}
+
+// Records that the existing Node to is a neighbour of the existing Node from, which is already part of from's adjacency.
+// In undirected mode, from becomes a neighbour of to as well, otherwise one of its predecessors.
+func (ne *NodeEnvironment) link(from, to NodeID) {
+	if !ne.predecessors[to].Contains(from) {
+		ne.predecessors[to] = append(append(NodeIDs{}, ne.predecessors[to]...), from)
+	}
+	if _, indexed := ne.edgeIndex[Edge{from, to}]; indexed {
+		return
+	}
+	ne.edgeIndex[Edge{from, to}] = len(ne.edges)
+	if ne.Undirected {
+		ne.edgeIndex[Edge{to, from}] = len(ne.edges)
+	}
+	ne.edges = append(ne.edges, Edge{from, to})
+}
+
+// Removes the Edges from the edge index. The remaining Edges keep their order.
+func (ne *NodeEnvironment) unindexEdges(edges ...Edge) {
+	removed := false
+	for _, edge := range edges {
+		if _, indexed := ne.edgeIndex[edge]; indexed {
+			delete(ne.edgeIndex, edge)
+			if ne.Undirected {
+				delete(ne.edgeIndex, edge.Reverse())
+			}
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	kept := make([]Edge, 0, len(ne.edges))
+	for idx, edge := range ne.edges {
+		if at, indexed := ne.edgeIndex[edge]; indexed && at == idx {
+			ne.edgeIndex[edge] = len(kept)
+			if ne.Undirected {
+				ne.edgeIndex[edge.Reverse()] = len(kept)
+			}
+			kept = append(kept, edge)
+		}
+	}
+	ne.edges = kept
+}
+
+// Returns a copy of the IDs without the ID.
+func withoutID(ids NodeIDs, id NodeID) NodeIDs {
+	filtered := NodeIDs{}
+	for _, x := range ids {
+		if x != id {
+			filtered = append(filtered, x)
+		}
+	}
+	return filtered
+}
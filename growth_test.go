@@ -0,0 +1,138 @@
+package gwc
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds a corridor Node, which grows the corridor by another Node until it reaches the given length.
+func newCorridorNode(id int, length int) Node {
+	return NewNode(strconv.Itoa(id), func(_ Random, env NodeEnvironment) NodeState {
+		if id+1 < length {
+			env.AddNode(newCorridorNode(id+1, length))
+			env.AddEdge(strconv.Itoa(id), strconv.Itoa(id+1))
+			return "corridor"
+		}
+		return "room"
+	})
+}
+
+func Test_GrowDuringCollapse(t *testing.T) {
+	score := func(id NodeID, _ NodeEnvironment) float64 {
+		return float64(len(id))
+	}
+	for _, order := range []CollapseOrderFn{AscendingCollapseOrder, RandomCollapseOrder, PriorityCollapseOrder(score), WavefrontCollapseOrder("0")} {
+		sim := New(rand.New(rand.NewSource(42)), order, Nodes{newCorridorNode(0, 5)}, WithUndirectedEdges(), WithDistanceCache())
+		env := sim.Collapse()
+
+		assert.EqualValues(t, NodeIDs{"0", "1", "2", "3", "4"}, env.Collapsed())
+		assert.EqualValues(t, NodeStates{"corridor", "corridor", "corridor", "corridor", "room"}, env.States())
+		assert.Len(t, env.NodesMap, 5)
+		assert.EqualValues(t, NodeIDs{"1", "3"}, env.Neighbours("2"))
+		assert.Equal(t, 4, env.Distance("4", "0"))
+		assert.Len(t, env.Edges(), 4)
+	}
+}
+
+func Test_CollapseHook(t *testing.T) {
+	// Every collapsed Node of the line spawns a leaf, which is then collapsed as well.
+	hook := func(env *NodeEnvironment, id NodeID) {
+		if len(id) == 1 {
+			env.AddNode(NewNode(id+"'", nil))
+			env.AddEdge(id, id+"'")
+		}
+	}
+	env := New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, newLinearNodes(), WithCollapseHook(hook)).Collapse()
+	assert.Len(t, env.Nodes, 8)
+	assert.EqualValues(t, NodeIDs{"0", "1", "2", "3", "0'", "1'", "2'", "3'"}, env.Collapsed())
+	assert.EqualValues(t, NodeIDs{"0", "2", "1'"}, env.Neighbours("1"))
+	assert.EqualValues(t, NodeIDs{"1"}, env.Predecessors("1'"))
+}
+
+func Test_RemoveNodeAndEdge(t *testing.T) {
+	sim := New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, newDefaultTestNodes(newAbcdNodeSuperposition()...))
+	env := sim.Collapse()
+	original := env.Nodes
+
+	env.RemoveNode("2")
+	env.RemoveNode("unknown")
+	assert.EqualValues(t, NodeIDs{"0", "1", "3", "4", "5", "6"}, env.Collapsed())
+	assert.Len(t, env.Nodes, 6)
+	assert.Len(t, original, 7)
+	assert.NotContains(t, env.NodesMap, "2")
+	assert.EqualValues(t, NodeIDs{"5"}, env.Neighbours("3"))
+	assert.EqualValues(t, NodeIDs{"5"}, env.Predecessors("3"))
+	assert.Equal(t, -1, env.Distance("0", "3"))
+
+	env.RemoveEdge("4", "5")
+	assert.EqualValues(t, NodeIDs{"6"}, env.Neighbours("4"))
+	assert.True(t, env.HasEdge("5", "4"))
+	assert.False(t, env.HasEdge("4", "5"))
+
+	// Removed Nodes can be re-added and collapsed again.
+	env.AddNode(NewSuperpositionNode("2", newAbcdNodeSuperposition(), "3"))
+	env = sim.CollapseSubset(env, NodeIDs{"2"})
+	assert.EqualValues(t, NodeIDs{"0", "1", "3", "4", "5", "6", "2"}, env.Collapsed())
+	assert.EqualValues(t, NodeIDs{"5", "2"}, env.Predecessors("3"))
+}
+
+func Test_GrowthKeepsIndices(t *testing.T) {
+	for _, undirected := range []bool{false, true} {
+		env := newNodeEnvironment(newDefaultTestNodes(nil), undirected)
+		version := env.version
+		env.beginChanges()
+		env.AddNode(NewNode("7", nil, "0", "8"))
+		env.AddEdge("3", "7")
+		env.RemoveNode("2")
+		env.RemoveEdge("4", "5")
+		env.AddNode(NewNode("8", nil, "6"))
+		env.AddNode(NewNode("2", nil, "7"))
+		env.endChanges()
+		assert.Equal(t, version+1, env.version)
+
+		// The incrementally updated indices match the ones built from scratch.
+		nodes := Nodes{}
+		for _, node := range env.Nodes {
+			nodes = append(nodes, NewNode(node.ID(), nil, env.Neighbours(node.ID())...))
+		}
+		rebuilt := newNodeEnvironment(nodes, undirected)
+		for _, node := range env.Nodes {
+			assert.ElementsMatch(t, rebuilt.Neighbours(node.ID()), env.Neighbours(node.ID()), node.ID())
+			assert.ElementsMatch(t, rebuilt.Predecessors(node.ID()), env.Predecessors(node.ID()), node.ID())
+		}
+		assert.Len(t, env.Edges(), len(rebuilt.Edges()))
+		for idx, edge := range env.Edges() {
+			assert.True(t, rebuilt.HasEdge(edge.From, edge.To), edge.String())
+			key, _ := env.edgeKey(edge.Reverse())
+			assert.Equal(t, undirected, key == env.Edges()[idx], edge.String())
+		}
+	}
+}
+
+func Test_GrowCopies(t *testing.T) {
+	sim := New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, newLinearNodes())
+	env := sim.Environment()
+	sibling := env
+	sibling.AddNode(NewNode("x", nil, "0"))
+	env.AddNode(NewNode("y", nil, "0"))
+	sibling.AddNode(NewNode("z", nil, "0"))
+	assert.EqualValues(t, NodeIDs{"1", "y"}, env.Predecessors("0"))
+	assert.EqualValues(t, NodeIDs{"1", "x", "z"}, sibling.Predecessors("0"))
+	assert.NotContains(t, env.NodesMap, "x")
+
+	// The collapse grows its own copy, which leaves the original environment intact.
+	hook := func(env *NodeEnvironment, id NodeID) {
+		env.AddNode(NewNode(id+"'", nil))
+	}
+	sim = New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, newLinearNodes(), WithCollapseHook(hook))
+	env = sim.Environment()
+	collapsed := sim.CollapseSubset(env, NodeIDs{"0"})
+	env.RemoveEdge("0", "1")
+	assert.Contains(t, collapsed.NodesMap, "0'")
+	assert.NotContains(t, env.NodesMap, "0'")
+	assert.False(t, env.HasEdge("0", "1"))
+	assert.True(t, collapsed.HasEdge("0", "1"))
+}
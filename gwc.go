@@ -1,38 +1,242 @@
 package gwc
 
-import (
-	"math/rand"
-)
-
-func New(rnd *rand.Rand, mode CollapseOrderFn, nodes Nodes) *GraphWaveCollapse {
-	return &GraphWaveCollapse{
+func New(rnd Random, mode CollapseOrderFn, nodes Nodes, opts ...Option) *GraphWaveCollapse {
+	gwc := &GraphWaveCollapse{
 		rnd:   rnd,
 		mode:  mode,
 		nodes: nodes,
 	}
+	for _, opt := range opts {
+		opt(gwc)
+	}
+	if gwc.validate != nil {
+		ignore := gwc.validate
+		// Asymmetric edges are symmetrised in undirected mode and therefore no error.
+		if gwc.undirected {
+			ignore = append(ignore, AsymmetricEdge)
+		}
+		if errs, ok := Validate(gwc.nodes).(ValidationErrors); ok {
+			if errs = errs.Without(ignore...); len(errs) > 0 {
+				gwc.err = errs
+			}
+		}
+	}
+	return gwc
 }
 
 type GraphWaveCollapse struct {
-	rnd   *rand.Rand
+	rnd   Random
 	mode  CollapseOrderFn
 	nodes Nodes
+	err   error
+
+	validate   []ValidationErrorKind
+	undirected bool
+	cache      bool
+	streams    bool
+	seed       uint64
+	edgeStates EdgeStateFns
+	edgeOrder  EdgeCollapseOrderFn
+	hook       CollapseHookFn
+}
+
+// CollapseHookFn is called after a Node has been collapsed. It may change the environment, e.g. grow the graph with AddNode() and AddEdge().
+type CollapseHookFn func(env *NodeEnvironment, id NodeID)
+
+// Option configures a GraphWaveCollapse created by New.
+type Option func(*GraphWaveCollapse)
+
+// Validates the Nodes when creating the GraphWaveCollapse, ignoring errors of the provided kinds.
+// If the Nodes are invalid, Err() returns the ValidationErrors and Collapse() returns an empty environment without collapsing any Node.
+func WithValidation(ignore ...ValidationErrorKind) Option {
+	return func(gwc *GraphWaveCollapse) {
+		gwc.validate = append([]ValidationErrorKind{}, ignore...)
+	}
+}
+
+// Treats the graph as undirected, so that every edge can be traversed in both directions.
+func WithUndirectedEdges() Option {
+	return func(gwc *GraphWaveCollapse) {
+		gwc.undirected = true
+	}
+}
+
+// Enables the distance cache of the collapsed NodeEnvironment. See NodeEnvironment.EnableDistanceCache().
+func WithDistanceCache() Option {
+	return func(gwc *GraphWaveCollapse) {
+		gwc.cache = true
+	}
+}
+
+// Gives every Node its own random stream, which is derived from the seed, the Node's ID and the number of times it has been collapsed.
+// Changing the superposition of a single Node then only changes the states of the Nodes that depend on it,
+// as long as the CollapseOrderFn doesn't depend on the random numbers. The CollapseOrderFn still uses the Random passed to New().
+func WithNodeStreams(seed uint64) Option {
+	return func(gwc *GraphWaveCollapse) {
+		gwc.streams = true
+		gwc.seed = seed
+	}
+}
+
+// Collapses the Edges alongside the Nodes, using the state functions provided for each Edge.
+// Before each Node, the EdgeCollapseOrderFn is asked for Edges to collapse; if it's nil, ConnectedEdgeCollapseOrder is used.
+func WithEdgeStates(states EdgeStateFns, order EdgeCollapseOrderFn) Option {
+	return func(gwc *GraphWaveCollapse) {
+		if order == nil {
+			order = ConnectedEdgeCollapseOrder
+		}
+		gwc.edgeStates = states
+		gwc.edgeOrder = order
+	}
+}
+
+// Calls the hook after every collapsed Node.
+func WithCollapseHook(hook CollapseHookFn) Option {
+	return func(gwc *GraphWaveCollapse) {
+		gwc.hook = hook
+	}
+}
+
+// Returns the error that occurred while creating the GraphWaveCollapse, if any.
+func (gwc *GraphWaveCollapse) Err() error {
+	return gwc.err
+}
+
+// Builds an uncollapsed NodeEnvironment of the Nodes, configured according to the options passed to New().
+func (gwc *GraphWaveCollapse) Environment() NodeEnvironment {
+	env := *newNodeEnvironment(gwc.nodes, gwc.undirected)
+	if gwc.cache {
+		env.EnableDistanceCache()
+	}
+	return env
 }
 
+// Collapses all Nodes. If the Nodes are invalid, see WithValidation(), an empty environment is returned instead,
+// as the Nodes may not even be suitable for building an environment, e.g. if one of them is nil.
 func (gwc *GraphWaveCollapse) Collapse() NodeEnvironment {
-	env := *NewNodeEnvironment(gwc.nodes)
+	if gwc.err != nil {
+		return *newNodeEnvironment(Nodes{}, gwc.undirected)
+	}
+	return gwc.collapse(gwc.Environment(), gwc.mode)
+}
+
+// Collapses all uncollapsed Nodes of an existing environment, e.g. one built by Expand(), using the CollapseOrderFn.
+func (gwc *GraphWaveCollapse) CollapseEnvironment(env NodeEnvironment) NodeEnvironment {
+	if gwc.err != nil {
+		return env
+	}
+	return gwc.collapse(env, gwc.mode)
+}
+
+// Collapses the Nodes with the provided IDs within an existing environment, e.g. one returned by Environment() or a previous collapse.
+// The CollapseOrderFn is restricted to these Nodes, see RestrictedCollapseOrder(). All other Nodes are left as they are.
+func (gwc *GraphWaveCollapse) CollapseSubset(env NodeEnvironment, ids NodeIDs) NodeEnvironment {
+	if gwc.err != nil {
+		return env
+	}
+	return gwc.collapse(env, RestrictedCollapseOrder(gwc.mode, ids))
+}
+
+// Re-collapses the Nodes with the provided IDs and all Nodes within margin hops of them, while keeping the states of all other Nodes.
+// The Nodes and their Edges are uncollapsed first, so that their superpositions only see the fixed states around them. Locked Nodes are kept as well.
+// The rerolled environment is returned as a copy; the provided environment is left unchanged.
+func (gwc *GraphWaveCollapse) Reroll(env NodeEnvironment, ids NodeIDs, margin uint) NodeEnvironment {
+	if gwc.err != nil {
+		return env
+	}
+	region := NewNodeIDSet()
+	for _, id := range ids {
+		if _, exists := env.NodesMap[id]; !exists {
+			continue
+		}
+		if margin == 0 {
+			region.Add(id)
+		} else {
+			region.Add(env.NodesWithinRangeOfIncl(id, margin)...)
+		}
+	}
+	region.Remove(env.Locked()...)
+
+	rerolled := env.clone()
+	rerolled.uncollapse(region.IDs()...)
+	if gwc.edgeStates != nil {
+		for _, id := range region.IDs() {
+			rerolled.uncollapseEdges(rerolled.IncidentEdges(id)...)
+		}
+	}
+	return gwc.collapse(rerolled, RestrictedCollapseOrder(gwc.mode, region.IDs()))
+}
 
+func (gwc *GraphWaveCollapse) collapse(env NodeEnvironment, order CollapseOrderFn) NodeEnvironment {
+	// Stateful CollapseOrderFns keep their state for this run within the environment.
+	env.orders = newOrderRun()
 	for {
+		// Collapse Edges first, as long as the edge order provides them.
+		if gwc.edgeStates != nil {
+			if edge := gwc.edgeOrder(gwc.rnd, env); isEdgeCollapsible(env, edge) {
+				gwc.collapseEdge(&env, edge)
+				continue
+			}
+		}
+
 		// Retrieve next NodeIndex according to mode.
-		next := gwc.mode(gwc.rnd, env)
-		if _, exists := env.NodesMap[next]; !exists {
+		next := order(gwc.rnd, env)
+		if !isCollapsible(env, next) {
 			break
 		}
 
-		// Collapse the chosen Node and mark it as such.
-		env.Current = next
-		env.StatesMap[next] = env.NodesMap[next].Collapse(gwc.rnd, env)
-		env.CollapsedMap[next] = len(env.CollapsedMap)
+		gwc.collapseNode(&env, next)
 	}
 
+	env.orders = nil
 	return env
 }
+
+// Collapses the Node and marks it as such.
+func (gwc *GraphWaveCollapse) collapseNode(env *NodeEnvironment, id NodeID) {
+	rnd := gwc.rnd
+	if gwc.streams {
+		if env.attempts == nil {
+			env.attempts = map[NodeID]int{}
+		}
+		rnd = NewXoshiro256(NodeSeed(gwc.seed, id, env.attempts[id]))
+		env.attempts[id]++
+	}
+
+	env.Current = id
+	env.CurrentEdge = Edge{}
+	env.deferChanges(true)
+	env.StatesMap[id] = env.NodesMap[id].Collapse(rnd, *env)
+	env.CollapsedMap[id] = len(env.CollapsedMap)
+	env.deferChanges(false)
+
+	// The hook's changes are applied immediately, but count as a single change of the graph.
+	if gwc.hook != nil {
+		env.beginChanges()
+		gwc.hook(env, id)
+		env.endChanges()
+	}
+}
+
+// Collapses the Edge and marks it as such.
+func (gwc *GraphWaveCollapse) collapseEdge(env *NodeEnvironment, edge Edge) {
+	edge, _ = env.edgeKey(edge)
+	rnd := gwc.rnd
+	if gwc.streams {
+		if env.edgeAttempts == nil {
+			env.edgeAttempts = map[Edge]int{}
+		}
+		rnd = NewXoshiro256(NodeSeed(gwc.seed, edge.String(), env.edgeAttempts[edge]))
+		env.edgeAttempts[edge]++
+	}
+
+	env.CurrentEdge = edge
+	var state NodeState
+	env.deferChanges(true)
+	if fn := gwc.edgeStates(edge); fn != nil {
+		state = fn(rnd, *env)
+	}
+	env.EdgeStatesMap[edge] = state
+	env.EdgeCollapsedMap[edge] = len(env.EdgeCollapsedMap)
+	env.deferChanges(false)
+}
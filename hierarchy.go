@@ -0,0 +1,72 @@
+package gwc
+
+// ExpandFn builds the fine Nodes of a coarse Node of the collapsed parent environment.
+// Their neighbours should only refer to each other; edges to the Nodes of other coarse Nodes are built by a BoundaryFn.
+type ExpandFn func(parent NodeEnvironment, id NodeID) Nodes
+
+// BoundaryFn returns the fine edges between the fine Nodes of two coarse Nodes, which are connected by an edge leading from one to the other.
+// In undirected environments it's called once per coarse edge and the returned edges are traversable in both directions.
+type BoundaryFn func(parent NodeEnvironment, from, to NodeID, fromIDs, toIDs NodeIDs) []Edge
+
+// Returns an ID for a fine Node of the coarse Node, which is unique as long as the local ID is unique within the coarse Node.
+func ChildNodeID(parent NodeID, local NodeID) NodeID {
+	return parent + "/" + local
+}
+
+// Expands every coarse Node of the parent environment into fine Nodes and connects the fine Nodes of neighbouring coarse Nodes.
+// All fine Nodes share a single environment, so that collapsing them, e.g. with GraphWaveCollapse.CollapseEnvironment(),
+// resolves the boundaries between siblings consistently. The fine environment links back to the parent, see ParentState().
+func Expand(parent NodeEnvironment, expand ExpandFn, connect BoundaryFn) NodeEnvironment {
+	nodes := Nodes{}
+	parentOf := map[NodeID]NodeID{}
+	children := map[NodeID]NodeIDs{}
+	for _, node := range parent.Nodes {
+		id := node.ID()
+		for _, child := range expand(parent, id) {
+			nodes = append(nodes, child)
+			parentOf[child.ID()] = id
+			children[id] = append(children[id], child.ID())
+		}
+	}
+
+	boundaries := []Edge{}
+	if connect != nil {
+		for _, edge := range parent.Edges() {
+			boundaries = append(boundaries, connect(parent, edge.From, edge.To, children[edge.From], children[edge.To])...)
+		}
+	}
+
+	env := *newNodeEnvironmentWithEdges(nodes, boundaries, parent.Undirected)
+	env.parent = &parent
+	env.parentOf = parentOf
+	return env
+}
+
+// Returns the parent environment this environment was expanded from, or nil.
+func (ne *NodeEnvironment) Parent() *NodeEnvironment {
+	return ne.parent
+}
+
+// Returns the ID of the coarse Node the Node was expanded from, or "" if it wasn't expanded from any.
+func (ne *NodeEnvironment) ParentID(id NodeID) NodeID {
+	return ne.parentOf[id]
+}
+
+// Returns the state of the coarse Node the Node was expanded from, or nil.
+func (ne *NodeEnvironment) ParentState(id NodeID) NodeState {
+	if ne.parent == nil {
+		return nil
+	}
+	return ne.parent.StatesMap[ne.parentOf[id]]
+}
+
+// Returns the IDs of the Nodes expanded from the coarse Node, in order of the Nodes.
+func (ne *NodeEnvironment) ExpandedFrom(parent NodeID) NodeIDs {
+	ids := NodeIDs{}
+	for _, node := range ne.Nodes {
+		if ne.parentOf[node.ID()] == parent {
+			ids = append(ids, node.ID())
+		}
+	}
+	return ids
+}
@@ -0,0 +1,53 @@
+package gwc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Expand(t *testing.T) {
+	// Biomes of the coarse graph: sea - land - land - sea
+	coarse := *NewUndirectedNodeEnvironment(newLinearNodes())
+	for id, biome := range map[NodeID]NodeState{"0": "sea", "1": "land", "2": "land", "3": "sea"} {
+		coarse.SetState(id, biome)
+	}
+
+	// Fine Nodes take the biome of their parent, except for land at the boundary to the sea, which turns into beach.
+	tile := func(_ Random, env NodeEnvironment) NodeState {
+		biome := env.ParentState(env.Current)
+		for _, ni := range env.Neighbours(env.Current) {
+			if biome == "land" && env.ParentState(ni) == "sea" {
+				return "beach"
+			}
+		}
+		return biome
+	}
+	// Every coarse Node expands into a line of two fine Nodes: a - b
+	expand := func(parent NodeEnvironment, id NodeID) Nodes {
+		return Nodes{
+			NewNode(ChildNodeID(id, "a"), tile, ChildNodeID(id, "b")),
+			NewNode(ChildNodeID(id, "b"), tile),
+		}
+	}
+	// Neighbouring coarse Nodes are connected from the b of one to the a of the other.
+	connect := func(parent NodeEnvironment, from, to NodeID, fromIDs, toIDs NodeIDs) []Edge {
+		return []Edge{{fromIDs[1], toIDs[0]}}
+	}
+
+	fine := Expand(coarse, expand, connect)
+	assert.Len(t, fine.Nodes, 8)
+	assert.Equal(t, &coarse, fine.Parent())
+	assert.Nil(t, coarse.Parent())
+	assert.Equal(t, "1", fine.ParentID("1/b"))
+	assert.Equal(t, "", fine.ParentID("unknown"))
+	assert.EqualValues(t, NodeIDs{"2/a", "2/b"}, fine.ExpandedFrom("2"))
+	assert.EqualValues(t, NodeIDs{"2/b", "1/b"}, fine.Neighbours("2/a"))
+	assert.EqualValues(t, NodeIDs{"3/a", "2/a"}, fine.Neighbours("2/b"))
+
+	env := New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, nil).CollapseEnvironment(fine)
+	assert.EqualValues(t, NodeStates{"sea", "sea", "beach", "land", "land", "beach", "sea", "sea"}, env.States())
+	assert.Equal(t, "land", env.ParentState("1/b"))
+	assert.Nil(t, coarse.ParentState("1"))
+}
@@ -0,0 +1,598 @@
+package gwc
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// EdgeMode defines how the edges of an imported graph are turned into neighbour lists.
+type EdgeMode int
+
+const (
+	// Edges are directed or undirected as declared by the imported graph.
+	EdgesAsDeclared EdgeMode = iota
+	// Every edge only adds its target to the neighbours of its source.
+	EdgesDirected
+	// Every edge adds both of its ends to each other's neighbours.
+	EdgesUndirected
+)
+
+// ImportOptions configures how imported graphs are turned into Nodes.
+type ImportOptions struct {
+	Edges EdgeMode
+	// Superposition returns the NodeSuperposition of an imported Node. If nil, all Nodes have an empty superposition.
+	Superposition func(id NodeID, attrs NodeAttributes) NodeSuperposition
+}
+
+// graphBuilder collects the nodes and edges of an imported graph in order of their appearance.
+type graphBuilder struct {
+	opts       ImportOptions
+	ids        NodeIDs
+	attrs      map[NodeID]NodeAttributes
+	neighbours map[NodeID]NodeIDs
+}
+
+func newGraphBuilder(opts ImportOptions) *graphBuilder {
+	return &graphBuilder{
+		opts:       opts,
+		ids:        NodeIDs{},
+		attrs:      map[NodeID]NodeAttributes{},
+		neighbours: map[NodeID]NodeIDs{},
+	}
+}
+
+func (b *graphBuilder) node(id NodeID, attrs NodeAttributes) {
+	existing, exists := b.attrs[id]
+	if !exists {
+		existing = NodeAttributes{}
+		b.attrs[id] = existing
+		b.ids = append(b.ids, id)
+	}
+	for k, v := range attrs {
+		existing[k] = v
+	}
+}
+
+func (b *graphBuilder) edge(from, to NodeID, directed bool) {
+	b.node(from, nil)
+	b.node(to, nil)
+
+	switch b.opts.Edges {
+	case EdgesDirected:
+		directed = true
+	case EdgesUndirected:
+		directed = false
+	}
+
+	b.neighbour(from, to)
+	if !directed {
+		b.neighbour(to, from)
+	}
+}
+
+func (b *graphBuilder) neighbour(id, other NodeID) {
+	for _, ni := range b.neighbours[id] {
+		if ni == other {
+			return
+		}
+	}
+	b.neighbours[id] = append(b.neighbours[id], other)
+}
+
+func (b *graphBuilder) build() Nodes {
+	nodes := make(Nodes, len(b.ids))
+	for i, id := range b.ids {
+		var super NodeSuperposition
+		if b.opts.Superposition != nil {
+			super = b.opts.Superposition(id, b.attrs[id])
+		}
+		nodes[i] = NewAttributeNode(id, SuperpositionStateFn(super), b.attrs[id], b.neighbours[id]...)
+	}
+	return nodes
+}
+
+// Imports the Nodes of a JSON graph of the following form:
+//
+//	{
+//	  "directed": false,
+//	  "nodes": [{"id": "a", "attributes": {"biome": "forest"}, "neighbours": ["b"]}, {"id": "b"}],
+//	  "edges": [{"source": "b", "target": "c"}]
+//	}
+//
+// Neighbour lists and edges may be used interchangeably. Non-string attribute values are kept as their JSON text.
+func ImportJSON(r io.Reader, opts ImportOptions) (Nodes, error) {
+	var graph struct {
+		Directed bool `json:"directed"`
+		Nodes    []struct {
+			ID         NodeID                     `json:"id"`
+			Attributes map[string]json.RawMessage `json:"attributes"`
+			Neighbours NodeIDs                    `json:"neighbours"`
+		} `json:"nodes"`
+		Edges []struct {
+			Source   NodeID `json:"source"`
+			Target   NodeID `json:"target"`
+			Directed *bool  `json:"directed"`
+		} `json:"edges"`
+	}
+	if err := json.NewDecoder(r).Decode(&graph); err != nil {
+		return nil, err
+	}
+
+	b := newGraphBuilder(opts)
+	for _, n := range graph.Nodes {
+		if n.ID == "" {
+			return nil, fmt.Errorf("json: node without id")
+		}
+		attrs := NodeAttributes{}
+		for k, raw := range n.Attributes {
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				attrs[k] = s
+			} else {
+				attrs[k] = string(raw)
+			}
+		}
+		b.node(n.ID, attrs)
+	}
+	for _, n := range graph.Nodes {
+		for _, ni := range n.Neighbours {
+			b.edge(n.ID, ni, graph.Directed)
+		}
+	}
+	for _, e := range graph.Edges {
+		if e.Source == "" || e.Target == "" {
+			return nil, fmt.Errorf("json: edge without source or target")
+		}
+		directed := graph.Directed
+		if e.Directed != nil {
+			directed = *e.Directed
+		}
+		b.edge(e.Source, e.Target, directed)
+	}
+
+	return b.build(), nil
+}
+
+// Imports the Nodes of the first graph within a GraphML document.
+// Node data is stored as attributes, named by their key's attr.name, and key defaults are applied.
+func ImportGraphML(r io.Reader, opts ImportOptions) (Nodes, error) {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	var doc struct {
+		Keys []struct {
+			ID      string  `xml:"id,attr"`
+			For     string  `xml:"for,attr"`
+			Name    string  `xml:"attr.name,attr"`
+			Default *string `xml:"default"`
+		} `xml:"key"`
+		Graphs []struct {
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []struct {
+				ID   string `xml:"id,attr"`
+				Data []data `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				Source   string `xml:"source,attr"`
+				Target   string `xml:"target,attr"`
+				Directed string `xml:"directed,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Graphs) == 0 {
+		return nil, fmt.Errorf("graphml: document contains no graph")
+	}
+	graph := doc.Graphs[0]
+
+	names := map[string]string{}
+	defaults := NodeAttributes{}
+	for _, k := range doc.Keys {
+		if k.For != "" && k.For != "node" && k.For != "all" {
+			continue
+		}
+		name := k.Name
+		if name == "" {
+			name = k.ID
+		}
+		names[k.ID] = name
+		if k.Default != nil {
+			defaults[name] = strings.TrimSpace(*k.Default)
+		}
+	}
+
+	b := newGraphBuilder(opts)
+	for _, n := range graph.Nodes {
+		if n.ID == "" {
+			return nil, fmt.Errorf("graphml: node without id")
+		}
+		attrs := NodeAttributes{}
+		for k, v := range defaults {
+			attrs[k] = v
+		}
+		for _, d := range n.Data {
+			if name, known := names[d.Key]; known {
+				attrs[name] = strings.TrimSpace(d.Value)
+			}
+		}
+		b.node(n.ID, attrs)
+	}
+	for _, e := range graph.Edges {
+		if e.Source == "" || e.Target == "" {
+			return nil, fmt.Errorf("graphml: edge without source or target")
+		}
+		directed := graph.EdgeDefault == "directed"
+		if e.Directed != "" {
+			directed = e.Directed == "true"
+		}
+		b.edge(e.Source, e.Target, directed)
+	}
+
+	return b.build(), nil
+}
+
+// Imports the Nodes of a Graphviz DOT graph.
+// Node attributes, including defaults set by node statements, are kept. Ports, edge and graph attributes are ignored.
+func ImportDOT(r io.Reader, opts ImportOptions) (Nodes, error) {
+	tokens, err := dotTokenize(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	p := &dotParser{tokens: tokens, b: newGraphBuilder(opts)}
+	if err := p.parseGraph(); err != nil {
+		return nil, err
+	}
+	return p.b.build(), nil
+}
+
+type dotToken struct {
+	text string
+	// id is true for identifiers, numerals and strings, false for punctuation and edge operators.
+	id bool
+	// quoted is true for quoted and HTML strings, which are never keywords.
+	quoted bool
+}
+
+func dotTokenize(r *bufio.Reader) ([]dotToken, error) {
+	tokens := []dotToken{}
+	line_start := true
+	for {
+		c, _, err := r.ReadRune()
+		if err == io.EOF {
+			return tokens, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case c == '\n':
+			line_start = true
+			continue
+		case unicode.IsSpace(c):
+			continue
+		case c == '#' && line_start:
+			// Preprocessor output lines are ignored.
+			r.ReadString('\n')
+			continue
+		}
+		line_start = false
+
+		switch {
+		case c == '/':
+			next, _, _ := r.ReadRune()
+			if next == '/' {
+				r.ReadString('\n')
+				line_start = true
+			} else if next == '*' {
+				prev := rune(0)
+				for {
+					c, _, err := r.ReadRune()
+					if err != nil {
+						return nil, fmt.Errorf("dot: unterminated comment")
+					}
+					if prev == '*' && c == '/' {
+						break
+					}
+					prev = c
+				}
+			} else {
+				return nil, fmt.Errorf("dot: unexpected '/'")
+			}
+		case c == '-':
+			next, _, _ := r.ReadRune()
+			if next == '-' || next == '>' {
+				tokens = append(tokens, dotToken{"-" + string(next), false, false})
+				continue
+			}
+			r.UnreadRune()
+			tokens = append(tokens, dotToken{"-" + readDotWord(r), true, false})
+		case strings.ContainsRune("{}[];,=:", c):
+			tokens = append(tokens, dotToken{string(c), false, false})
+		case c == '"':
+			var sb strings.Builder
+			for {
+				c, _, err := r.ReadRune()
+				if err != nil {
+					return nil, fmt.Errorf("dot: unterminated string")
+				}
+				if c == '"' {
+					break
+				}
+				if c == '\\' {
+					next, _, _ := r.ReadRune()
+					if next == '\n' {
+						continue
+					}
+					if next != '"' {
+						sb.WriteRune(c)
+					}
+					c = next
+				}
+				sb.WriteRune(c)
+			}
+			tokens = append(tokens, dotToken{sb.String(), true, true})
+		case c == '<':
+			var sb strings.Builder
+			depth := 1
+			for depth > 0 {
+				c, _, err := r.ReadRune()
+				if err != nil {
+					return nil, fmt.Errorf("dot: unterminated html string")
+				}
+				if c == '<' {
+					depth++
+				} else if c == '>' {
+					depth--
+				}
+				if depth > 0 {
+					sb.WriteRune(c)
+				}
+			}
+			tokens = append(tokens, dotToken{sb.String(), true, true})
+		case isDotWordRune(c):
+			r.UnreadRune()
+			tokens = append(tokens, dotToken{readDotWord(r), true, false})
+		default:
+			return nil, fmt.Errorf("dot: unexpected %q", c)
+		}
+	}
+}
+
+func isDotWordRune(c rune) bool {
+	return c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c) || c > unicode.MaxASCII
+}
+
+func readDotWord(r *bufio.Reader) string {
+	var sb strings.Builder
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			break
+		}
+		if !isDotWordRune(c) {
+			r.UnreadRune()
+			break
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+type dotParser struct {
+	tokens   []dotToken
+	pos      int
+	b        *graphBuilder
+	directed bool
+}
+
+func (p *dotParser) peek() dotToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return dotToken{}
+}
+
+func (p *dotParser) next() dotToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *dotParser) keyword(t dotToken, kw string) bool {
+	return t.id && !t.quoted && strings.EqualFold(t.text, kw)
+}
+
+func (p *dotParser) punct(t dotToken, s string) bool {
+	return !t.id && t.text == s
+}
+
+func (p *dotParser) expect(s string) error {
+	if t := p.next(); !p.punct(t, s) {
+		return fmt.Errorf("dot: expected %q, got %q", s, t.text)
+	}
+	return nil
+}
+
+func (p *dotParser) parseGraph() error {
+	if p.keyword(p.peek(), "strict") {
+		p.next()
+	}
+	switch t := p.next(); {
+	case p.keyword(t, "graph"):
+		p.directed = false
+	case p.keyword(t, "digraph"):
+		p.directed = true
+	default:
+		return fmt.Errorf("dot: expected graph or digraph, got %q", t.text)
+	}
+	if p.peek().id {
+		p.next()
+	}
+	_, err := p.parseBlock(NodeAttributes{})
+	return err
+}
+
+// Parses a statement list in braces and returns the IDs of all nodes within it.
+func (p *dotParser) parseBlock(defaults NodeAttributes) (NodeIDs, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	// Node defaults are scoped to the block.
+	scoped := NodeAttributes{}
+	for k, v := range defaults {
+		scoped[k] = v
+	}
+
+	ids := NodeIDs{}
+	for {
+		t := p.peek()
+		switch {
+		case p.punct(t, "}"):
+			p.next()
+			return ids, nil
+		case t.text == "" && !t.id:
+			return nil, fmt.Errorf("dot: unexpected end of input")
+		case p.punct(t, ";"):
+			p.next()
+			continue
+		}
+
+		stmt_ids, err := p.parseStatement(scoped)
+		if err != nil {
+			return nil, err
+		}
+		ids = ids.Or(stmt_ids)
+	}
+}
+
+func (p *dotParser) parseStatement(defaults NodeAttributes) (NodeIDs, error) {
+	t := p.peek()
+
+	if p.keyword(t, "node") || p.keyword(t, "edge") || p.keyword(t, "graph") {
+		p.next()
+		attrs, err := p.parseAttrLists()
+		if err != nil {
+			return nil, err
+		}
+		if p.keyword(t, "node") {
+			for k, v := range attrs {
+				defaults[k] = v
+			}
+		}
+		return NodeIDs{}, nil
+	}
+
+	if t.id && len(p.tokens) > p.pos+1 && p.punct(p.tokens[p.pos+1], "=") {
+		p.pos += 2
+		if !p.next().id {
+			return nil, fmt.Errorf("dot: expected value after %q", t.text)
+		}
+		return NodeIDs{}, nil
+	}
+
+	// Parse the first operand, followed by an optional chain of edges.
+	operands := []NodeIDs{}
+	single := NodeID("")
+	for {
+		ids, id, err := p.parseOperand(defaults)
+		if err != nil {
+			return nil, err
+		}
+		if len(operands) == 0 {
+			single = id
+		}
+		operands = append(operands, ids)
+
+		op := p.peek()
+		if !p.punct(op, "--") && !p.punct(op, "->") {
+			break
+		}
+		p.next()
+	}
+
+	attrs, err := p.parseAttrLists()
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) == 1 && single != "" {
+		p.b.node(single, attrs)
+	}
+	for i := 1; i < len(operands); i++ {
+		for _, from := range operands[i-1] {
+			for _, to := range operands[i] {
+				p.b.edge(from, to, p.directed)
+			}
+		}
+	}
+
+	all := NodeIDs{}
+	for _, ids := range operands {
+		all = all.Or(ids)
+	}
+	return all, nil
+}
+
+// Parses a node ID or a subgraph. The returned ID is only set for plain node IDs.
+func (p *dotParser) parseOperand(defaults NodeAttributes) (NodeIDs, NodeID, error) {
+	t := p.peek()
+	if p.keyword(t, "subgraph") || p.punct(t, "{") {
+		if p.keyword(t, "subgraph") {
+			p.next()
+			if p.peek().id {
+				p.next()
+			}
+		}
+		ids, err := p.parseBlock(defaults)
+		return ids, "", err
+	}
+
+	t = p.next()
+	if !t.id {
+		return nil, "", fmt.Errorf("dot: expected node id, got %q", t.text)
+	}
+	// Ports are ignored.
+	for p.punct(p.peek(), ":") {
+		p.next()
+		p.next()
+	}
+
+	if _, exists := p.b.attrs[t.text]; !exists {
+		p.b.node(t.text, defaults)
+	}
+	return NodeIDs{t.text}, t.text, nil
+}
+
+func (p *dotParser) parseAttrLists() (NodeAttributes, error) {
+	attrs := NodeAttributes{}
+	for p.punct(p.peek(), "[") {
+		p.next()
+		for !p.punct(p.peek(), "]") {
+			k := p.next()
+			if !k.id {
+				return nil, fmt.Errorf("dot: expected attribute name, got %q", k.text)
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			v := p.next()
+			if !v.id {
+				return nil, fmt.Errorf("dot: expected attribute value, got %q", v.text)
+			}
+			attrs[k.text] = v.text
+			if t := p.peek(); p.punct(t, ",") || p.punct(t, ";") {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	return attrs, nil
+}
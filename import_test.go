@@ -0,0 +1,155 @@
+package gwc
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func neighboursOf(nodes Nodes) map[NodeID]NodeIDs {
+	neighbours := map[NodeID]NodeIDs{}
+	for _, node := range nodes {
+		neighbours[node.ID()] = node.Neighbours()
+	}
+	return neighbours
+}
+
+func Test_ImportJSON(t *testing.T) {
+	src := `{
+		"directed": false,
+		"nodes": [
+			{"id": "a", "attributes": {"biome": "forest", "height": 3}, "neighbours": ["b"]},
+			{"id": "b"}
+		],
+		"edges": [{"source": "b", "target": "c", "directed": true}]
+	}`
+
+	nodes, err := ImportJSON(strings.NewReader(src), ImportOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 3)
+	assert.EqualValues(t, map[NodeID]NodeIDs{
+		"a": {"b"},
+		"b": {"a", "c"},
+		"c": nil,
+	}, neighboursOf(nodes))
+
+	env := NewNodeEnvironment(nodes)
+	assert.Equal(t, NodeAttributes{"biome": "forest", "height": "3"}, env.Attributes("a"))
+	assert.Equal(t, NodeAttributes{}, env.Attributes("c"))
+
+	nodes, err = ImportJSON(strings.NewReader(src), ImportOptions{Edges: EdgesDirected})
+	assert.NoError(t, err)
+	assert.EqualValues(t, NodeIDs{"c"}, neighboursOf(nodes)["b"])
+
+	_, err = ImportJSON(strings.NewReader(`{"nodes": [{}]}`), ImportOptions{})
+	assert.Error(t, err)
+}
+
+func Test_ImportGraphML(t *testing.T) {
+	src := `<?xml version="1.0" encoding="UTF-8"?>
+	<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+		<key id="d0" for="node" attr.name="biome" attr.type="string"><default>plains</default></key>
+		<key id="d1" for="edge" attr.name="weight" attr.type="double"/>
+		<graph id="G" edgedefault="directed">
+			<node id="n0"><data key="d0">forest</data></node>
+			<node id="n1"/>
+			<edge source="n0" target="n1"><data key="d1">1.0</data></edge>
+			<edge source="n1" target="n2" directed="false"/>
+		</graph>
+	</graphml>`
+
+	nodes, err := ImportGraphML(strings.NewReader(src), ImportOptions{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[NodeID]NodeIDs{
+		"n0": {"n1"},
+		"n1": {"n2"},
+		"n2": {"n1"},
+	}, neighboursOf(nodes))
+
+	env := NewNodeEnvironment(nodes)
+	assert.Equal(t, NodeAttributes{"biome": "forest"}, env.Attributes("n0"))
+	assert.Equal(t, NodeAttributes{"biome": "plains"}, env.Attributes("n1"))
+
+	nodes, err = ImportGraphML(strings.NewReader(src), ImportOptions{Edges: EdgesUndirected})
+	assert.NoError(t, err)
+	assert.EqualValues(t, NodeIDs{"n1"}, neighboursOf(nodes)["n0"])
+	assert.EqualValues(t, NodeIDs{"n0", "n2"}, neighboursOf(nodes)["n1"])
+
+	_, err = ImportGraphML(strings.NewReader(`<graphml></graphml>`), ImportOptions{})
+	assert.Error(t, err)
+}
+
+func Test_ImportDOT(t *testing.T) {
+	src := `// A small dungeon.
+	strict graph dungeon {
+		graph [rankdir=LR];
+		node [shape=box, kind="room"];
+		a [label="Entrance \"A\""];
+		a -- b -- c;
+		subgraph cluster_1 {
+			node [kind=hall];
+			d; e:n
+		}
+		c -- {d e} /* both halls */
+		f = g
+		"x y" [kind=secret]
+	}`
+
+	nodes, err := ImportDOT(strings.NewReader(src), ImportOptions{
+		Superposition: func(id NodeID, attrs NodeAttributes) NodeSuperposition {
+			return NodeSuperposition{
+				func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
+					return 1, attrs["kind"]
+				},
+			}
+		},
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[NodeID]NodeIDs{
+		"a":   {"b"},
+		"b":   {"a", "c"},
+		"c":   {"b", "d", "e"},
+		"d":   {"c"},
+		"e":   {"c"},
+		"x y": nil,
+	}, neighboursOf(nodes))
+
+	env := NewNodeEnvironment(nodes)
+	assert.Equal(t, NodeAttributes{"shape": "box", "kind": "room", "label": `Entrance "A"`}, env.Attributes("a"))
+	assert.Equal(t, "hall", env.Attributes("d")["kind"])
+
+	collapsed := New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, nodes).Collapse()
+	assert.EqualValues(t, NodeStates{"room", "room", "room", "hall", "hall", "secret"}, collapsed.States())
+
+	nodes, err = ImportDOT(strings.NewReader(`digraph { a -> b -> a; b -> c }`), ImportOptions{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[NodeID]NodeIDs{
+		"a": {"b"},
+		"b": {"a", "c"},
+		"c": nil,
+	}, neighboursOf(nodes))
+
+	// Quoted keywords are plain IDs.
+	nodes, err = ImportDOT(strings.NewReader(`digraph { "node" -> "edge"; "graph" -> "subgraph" }`), ImportOptions{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, map[NodeID]NodeIDs{
+		"node":     {"edge"},
+		"edge":     nil,
+		"graph":    {"subgraph"},
+		"subgraph": nil,
+	}, neighboursOf(nodes))
+
+	invalid := []string{
+		`graph { a -- }`,
+		`tree { a }`,
+		`graph { a [b] }`,
+		`graph { "a }`,
+		`graph { a `,
+	}
+	for _, src := range invalid {
+		_, err := ImportDOT(strings.NewReader(src), ImportOptions{})
+		assert.Error(t, err, src)
+	}
+}
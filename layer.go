@@ -0,0 +1,142 @@
+package gwc
+
+// NodeLayer is a named layer of a cell, e.g. its terrain or vegetation, which is collapsed with its own state function.
+type NodeLayer struct {
+	Name string
+	Fn   NodeStateFn
+}
+
+// LayerNode carries a single layer of a cell. Every layer of a cell is a Node of its own, so that the layers can be scheduled independently.
+// It's connected to the same layer of the neighbouring cells and to the other layers of its own cell.
+type LayerNode struct {
+	BaseNode
+	cell   NodeID
+	layer  string
+	layers []string
+	cells  NodeIDs
+}
+
+// Returns the ID of the Node carrying the layer of the cell.
+func LayerNodeID(cell NodeID, layer string) NodeID {
+	return cell + "@" + layer
+}
+
+// Builds the Nodes of a cell, one per layer. The layers are listed bottom to top, neighbours are the IDs of the neighbouring cells.
+func NewLayeredNodes(cell NodeID, layers []NodeLayer, neighbours ...NodeID) Nodes {
+	names := make([]string, len(layers))
+	for idx, layer := range layers {
+		names[idx] = layer.Name
+	}
+
+	nodes := make(Nodes, len(layers))
+	for idx, layer := range layers {
+		ids := NodeIDs{}
+		for _, ni := range neighbours {
+			ids = append(ids, LayerNodeID(ni, layer.Name))
+		}
+		for _, other := range names {
+			if other != layer.Name {
+				ids = append(ids, LayerNodeID(cell, other))
+			}
+		}
+		nodes[idx] = &LayerNode{BaseNode{LayerNodeID(cell, layer.Name), ids, layer.Fn}, cell, layer.Name, names, neighbours}
+	}
+	return nodes
+}
+
+func (n *LayerNode) Cell() NodeID {
+	return n.cell
+}
+
+func (n *LayerNode) Layer() string {
+	return n.layer
+}
+
+// Returns the names of all layers of the cell, bottom to top.
+func (n *LayerNode) Layers() []string {
+	return n.layers
+}
+
+// Returns the IDs of the neighbouring cells.
+func (n *LayerNode) CellNeighbours() NodeIDs {
+	return n.cells
+}
+
+// Returns the cell and the layer of the Node being collapsed, or empty strings if it isn't a LayerNode.
+func (ne *NodeEnvironment) CurrentLayer() (NodeID, string) {
+	if node, ok := ne.NodesMap[ne.Current].(*LayerNode); ok {
+		return node.cell, node.layer
+	}
+	return "", ""
+}
+
+// Returns the state of the cell's layer, or nil if it's uncollapsed.
+func (ne *NodeEnvironment) LayerState(cell NodeID, layer string) NodeState {
+	return ne.StatesMap[LayerNodeID(cell, layer)]
+}
+
+// Returns the IDs of the cells neighbouring the cell of the LayerNode, or nil if it isn't a LayerNode.
+func (ne *NodeEnvironment) CellNeighbours(id NodeID) NodeIDs {
+	if node, ok := ne.NodesMap[id].(*LayerNode); ok {
+		return node.cells
+	}
+	return nil
+}
+
+// Produces a CollapseOrderFn that collapses the layers one after another, in the order of the provided layer names.
+// Within each layer, the Nodes are chosen by the order, see RestrictedCollapseOrder(). Remaining Nodes are collapsed last.
+func LayerByLayerCollapseOrder(order CollapseOrderFn, layers ...string) CollapseOrderFn {
+	key := &orderKey{"layer by layer"}
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		state := env.orderState(key, func() interface{} { return &layerByLayerState{} }).(*layerByLayerState)
+		if state.tracker.reset(env) {
+			ids := make(map[string]NodeIDs, len(layers))
+			for _, node := range env.Nodes {
+				if node, ok := node.(*LayerNode); ok {
+					ids[node.layer] = append(ids[node.layer], node.ID())
+				}
+			}
+			orders := make([]CollapseOrderFn, 0, len(layers)+1)
+			for _, layer := range layers {
+				orders = append(orders, RestrictedCollapseOrder(order, ids[layer]))
+			}
+			state.chain = ChainCollapseOrder(append(orders, order)...)
+		}
+		return state.chain(rnd, env)
+	}
+}
+
+type layerByLayerState struct {
+	tracker orderTracker
+	chain   CollapseOrderFn
+}
+
+// Produces a CollapseOrderFn that collapses all layers of a cell, bottom to top, before continuing with the next cell chosen by the order.
+func InterleavedLayerCollapseOrder(order CollapseOrderFn) CollapseOrderFn {
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		if _, collapsed := env.CollapsedMap[env.Current]; collapsed {
+			if next := lowestUncollapsedLayer(env, env.Current); next != "" {
+				return next
+			}
+		}
+		next := order(rnd, env)
+		if lowest := lowestUncollapsedLayer(env, next); lowest != "" {
+			return lowest
+		}
+		return next
+	}
+}
+
+// Returns the lowest uncollapsed layer of the LayerNode's cell, or "" if there is none.
+func lowestUncollapsedLayer(env NodeEnvironment, id NodeID) NodeID {
+	node, ok := env.NodesMap[id].(*LayerNode)
+	if !ok {
+		return ""
+	}
+	for _, layer := range node.layers {
+		if lid := LayerNodeID(node.cell, layer); isCollapsible(env, lid) {
+			return lid
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package gwc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLayeredTestNodes() Nodes {
+	// The cells form a line: 0 - 1 - 2 - 3
+	// The terrain is water in the first two cells and grass in the others.
+	terrain := func(_ Random, env NodeEnvironment) NodeState {
+		if cell, _ := env.CurrentLayer(); cell < "2" {
+			return "water"
+		}
+		return "grass"
+	}
+	// Vegetation depends on the cell's own terrain and grows trees on grass next to grass only.
+	vegetation := func(_ Random, env NodeEnvironment) NodeState {
+		cell, _ := env.CurrentLayer()
+		if env.LayerState(cell, "terrain") == "water" {
+			return "reeds"
+		}
+		for _, ni := range env.CellNeighbours(env.Current) {
+			if env.LayerState(ni, "terrain") != "grass" {
+				return "bushes"
+			}
+		}
+		return "trees"
+	}
+	layers := []NodeLayer{{"terrain", terrain}, {"vegetation", vegetation}}
+
+	nodes := Nodes{}
+	nodes = append(nodes, NewLayeredNodes("0", layers, "1")...)
+	nodes = append(nodes, NewLayeredNodes("1", layers, "0", "2")...)
+	nodes = append(nodes, NewLayeredNodes("2", layers, "1", "3")...)
+	nodes = append(nodes, NewLayeredNodes("3", layers, "2")...)
+	return nodes
+}
+
+func Test_LayeredNodes(t *testing.T) {
+	nodes := newLayeredTestNodes()
+	assert.Len(t, nodes, 8)
+	assert.NoError(t, Validate(nodes))
+
+	node := nodes[3].(*LayerNode)
+	assert.Equal(t, "1@vegetation", node.ID())
+	assert.Equal(t, "1", node.Cell())
+	assert.Equal(t, "vegetation", node.Layer())
+	assert.Equal(t, []string{"terrain", "vegetation"}, node.Layers())
+	assert.EqualValues(t, NodeIDs{"0", "2"}, node.CellNeighbours())
+	assert.EqualValues(t, NodeIDs{"0@vegetation", "2@vegetation", "1@terrain"}, node.Neighbours())
+
+	env := NewNodeEnvironment(nodes)
+	assert.Nil(t, env.CellNeighbours("unknown"))
+	env.Current = "unknown"
+	cell, layer := env.CurrentLayer()
+	assert.Equal(t, "", cell)
+	assert.Equal(t, "", layer)
+}
+
+func Test_LayerByLayerCollapseOrder(t *testing.T) {
+	order := LayerByLayerCollapseOrder(DescendingCollapseOrder, "terrain", "vegetation")
+	env := New(rand.New(rand.NewSource(42)), order, newLayeredTestNodes()).Collapse()
+
+	assert.EqualValues(t, NodeIDs{
+		"3@terrain", "2@terrain", "1@terrain", "0@terrain",
+		"3@vegetation", "2@vegetation", "1@vegetation", "0@vegetation",
+	}, env.Collapsed())
+	assert.EqualValues(t, NodeStates{"water", "reeds", "water", "reeds", "grass", "bushes", "grass", "trees"}, env.States())
+
+	// The order starts over for every collapse, even if collapses share it concurrently.
+	done := make(chan NodeIDs)
+	for i := 0; i < 4; i++ {
+		go func() {
+			env := New(rand.New(rand.NewSource(42)), order, newLayeredTestNodes()).Collapse()
+			done <- env.Collapsed()
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		assert.EqualValues(t, env.Collapsed(), <-done)
+	}
+}
+
+func Test_InterleavedLayerCollapseOrder(t *testing.T) {
+	order := InterleavedLayerCollapseOrder(DescendingCollapseOrder)
+	env := New(rand.New(rand.NewSource(42)), order, newLayeredTestNodes()).Collapse()
+
+	assert.EqualValues(t, NodeIDs{
+		"3@terrain", "3@vegetation", "2@terrain", "2@vegetation",
+		"1@terrain", "1@vegetation", "0@terrain", "0@vegetation",
+	}, env.Collapsed())
+	// The vegetation of cell 3 has been collapsed before the terrain of cell 2 existed.
+	assert.Equal(t, "bushes", env.LayerState("3", "vegetation"))
+	assert.Equal(t, "bushes", env.LayerState("2", "vegetation"))
+}
@@ -0,0 +1,124 @@
+package gwc
+
+type (
+	// EdgeLabel distinguishes different kinds of edges between two neighbouring Nodes.
+	EdgeLabel = interface{}
+	// EdgeLabelFn returns the label of the edge leading from one Node to its neighbour.
+	EdgeLabelFn = func(env NodeEnvironment, from, to NodeID) EdgeLabel
+)
+
+// LearnedPair is the key of a co-occurrence of two states at a certain distance.
+// The Label is only set for direct neighbours (Distance 1).
+type LearnedPair struct {
+	Label    EdgeLabel
+	Distance uint
+	From, To NodeState
+}
+
+// Builds a Learner that counts co-occurrences of states up to the given distance.
+// If label is not nil, co-occurrences of direct neighbours are additionally distinguished by their edge's label.
+func NewLearner(maxDistance uint, label EdgeLabelFn) *Learner {
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+	return &Learner{
+		MaxDistance: maxDistance,
+		Label:       label,
+		States:      NodeStates{},
+		Frequencies: map[NodeState]int{},
+		Pairs:       map[LearnedPair]int{},
+	}
+}
+
+// Learner derives states, weights and adjacency rules from example NodeEnvironments.
+// All states need to be comparable, as they're used as map keys.
+type Learner struct {
+	MaxDistance uint
+	Label       EdgeLabelFn
+	// States contains all learned states in order of their first occurrence.
+	States      NodeStates
+	Frequencies map[NodeState]int
+	Pairs       map[LearnedPair]int
+}
+
+// Counts the states and co-occurrences of all Nodes in the examples, which have an entry in their StatesMap.
+func (l *Learner) Learn(examples ...NodeEnvironment) *Learner {
+	for _, env := range examples {
+		for _, node := range env.Nodes {
+			id := node.ID()
+			state, known := env.StatesMap[id]
+			if !known {
+				continue
+			}
+			if _, seen := l.Frequencies[state]; !seen {
+				l.States = append(l.States, state)
+			}
+			l.Frequencies[state]++
+
+			for other, distance := range learnerDistances(env, id, l.MaxDistance) {
+				other_state, known := env.StatesMap[other]
+				if !known || distance == 0 {
+					continue
+				}
+				l.Pairs[l.pair(env, id, other, distance, state, other_state)]++
+			}
+		}
+	}
+	return l
+}
+
+func (l *Learner) pair(env NodeEnvironment, from, to NodeID, distance int, a, b NodeState) LearnedPair {
+	var label EdgeLabel
+	if distance == 1 && l.Label != nil {
+		label = l.Label(env, from, to)
+	}
+	return LearnedPair{label, uint(distance), a, b}
+}
+
+// Returns the relative frequency of the state within all examples.
+func (l *Learner) Probability(state NodeState) NodeProbability {
+	total := 0
+	for _, n := range l.Frequencies {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	return NodeProbability(l.Frequencies[state]) / NodeProbability(total)
+}
+
+// Returns whether state b has been observed at the given distance (and label) of state a.
+func (l *Learner) Allows(label EdgeLabel, distance uint, a, b NodeState) bool {
+	return l.Pairs[LearnedPair{label, distance, a, b}] > 0
+}
+
+// Builds a NodeSuperposition containing one function per learned state.
+// Each state is weighted by its absolute number of occurrences, unless it never co-occurred with the state of an already collapsed Node in range.
+func (l *Learner) Superposition() NodeSuperposition {
+	super := make(NodeSuperposition, len(l.States))
+	for i := range l.States {
+		state := l.States[i]
+		super[i] = func(_ Random, env NodeEnvironment) (NodeProbability, NodeState) {
+			for other, distance := range learnerDistances(env, env.Current, l.MaxDistance) {
+				if _, collapsed := env.CollapsedMap[other]; !collapsed || distance == 0 {
+					continue
+				}
+				if l.Pairs[l.pair(env, env.Current, other, distance, state, env.StatesMap[other])] == 0 {
+					return 0, state
+				}
+			}
+			return NodeProbability(l.Frequencies[state]), state
+		}
+	}
+	return super
+}
+
+// Returns the distances of all Nodes within range of the given Node.
+func learnerDistances(env NodeEnvironment, id NodeID, depth uint) map[NodeID]int {
+	r := env.search(id, int(depth))
+	distances := make(map[NodeID]int, len(r.distances))
+	for _, ni := range r.within(int(depth)) {
+		distances[ni] = r.distances[ni]
+	}
+	return distances
+}
@@ -0,0 +1,58 @@
+package gwc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLearnerExample() NodeEnvironment {
+	// The example has the form:
+	// A - B - A - B
+	env := NewNodeEnvironment(newLinearNodes())
+	env.StatesMap = NodeStatesMap{"0": "A", "1": "B", "2": "A", "3": "B"}
+	return *env
+}
+
+func Test_Learner(t *testing.T) {
+	l := NewLearner(2, nil).Learn(newLearnerExample())
+
+	assert.EqualValues(t, NodeStates{"A", "B"}, l.States)
+	assert.Equal(t, 2, l.Frequencies["A"])
+	assert.Equal(t, 0.5, l.Probability("B"))
+	assert.Equal(t, 0.0, l.Probability("C"))
+
+	assert.True(t, l.Allows(nil, 1, "A", "B"))
+	assert.True(t, l.Allows(nil, 1, "B", "A"))
+	assert.False(t, l.Allows(nil, 1, "A", "A"))
+	assert.True(t, l.Allows(nil, 2, "A", "A"))
+	assert.False(t, l.Allows(nil, 2, "A", "B"))
+	assert.Equal(t, 3, l.Pairs[LearnedPair{nil, 1, "B", "A"}])
+
+	// Collapsing a new graph with the learned superposition yields the alternating pattern again.
+	nodes := newDefaultTestNodes(l.Superposition()...)
+	env := New(rand.New(rand.NewSource(42)), RandomStreakCollapseOrder, nodes).Collapse()
+	for _, node := range env.Nodes {
+		for _, ni := range node.Neighbours() {
+			assert.NotEqual(t, env.StatesMap[node.ID()], env.StatesMap[ni])
+		}
+	}
+}
+
+func Test_LearnerLabels(t *testing.T) {
+	// Edges leading to a higher NodeID are labeled "up", the others "down".
+	label := func(_ NodeEnvironment, from, to NodeID) EdgeLabel {
+		if to > from {
+			return "up"
+		}
+		return "down"
+	}
+	l := NewLearner(0, label).Learn(newLearnerExample())
+
+	assert.Equal(t, uint(1), l.MaxDistance)
+	assert.True(t, l.Allows("up", 1, "A", "B"))
+	assert.True(t, l.Allows("down", 1, "A", "B"))
+	assert.False(t, l.Allows(nil, 1, "A", "B"))
+	assert.False(t, l.Allows("up", 2, "A", "A"))
+}
@@ -0,0 +1,46 @@
+package gwc
+
+// Returns the states of the Node's collapsed neighbours, in order of its neighbour list.
+func (ne *NodeEnvironment) NeighbourStates(id NodeID) NodeStates {
+	states := NodeStates{}
+	for _, ni := range ne.Neighbours(id) {
+		if _, collapsed := ne.CollapsedMap[ni]; collapsed {
+			states = append(states, ne.StatesMap[ni])
+		}
+	}
+	return states
+}
+
+// Counts the states of the collapsed Nodes among the IDs. The states need to be comparable, as they're used as map keys.
+func (ne *NodeEnvironment) StateHistogram(ids NodeIDs) map[NodeState]int {
+	histogram := map[NodeState]int{}
+	for _, id := range ids {
+		if _, collapsed := ne.CollapsedMap[id]; collapsed {
+			histogram[ne.StatesMap[id]]++
+		}
+	}
+	return histogram
+}
+
+// Counts the collapsed Nodes among the IDs that are in the given state.
+func (ne *NodeEnvironment) CountInState(ids NodeIDs, state NodeState) int {
+	count := 0
+	for _, id := range ids {
+		if _, collapsed := ne.CollapsedMap[id]; collapsed && StatesEqual(ne.StatesMap[id], state) {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns the closest collapsed Node in the given state within range of the given Node, which may be the Node itself, and its distance.
+// Returns "" and -1 if there is no such Node.
+func (ne *NodeEnvironment) NearestInState(id NodeID, state NodeState, maxDepth uint) (NodeID, int) {
+	r := ne.search(id, int(maxDepth))
+	for _, ni := range r.within(int(maxDepth)) {
+		if _, collapsed := ne.CollapsedMap[ni]; collapsed && StatesEqual(ne.StatesMap[ni], state) {
+			return ni, r.distances[ni]
+		}
+	}
+	return "", -1
+}
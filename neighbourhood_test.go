@@ -0,0 +1,36 @@
+package gwc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NeighbourhoodHelpers(t *testing.T) {
+	ne := newDefaultTestNodesEnvironment()
+	for i, state := range map[NodeID]NodeState{"0": "water", "1": "grass", "3": "water", "6": "grass"} {
+		ne.StatesMap[i] = state
+		ne.CollapsedMap[i] = len(ne.CollapsedMap)
+	}
+	// Uncollapsed Nodes are ignored, even if they have a state.
+	ne.StatesMap["4"] = "water"
+
+	assert.EqualValues(t, NodeStates{"water", "grass", "water"}, ne.NeighbourStates("2"))
+	assert.EqualValues(t, NodeStates{"water"}, ne.NeighbourStates("5"))
+	assert.EqualValues(t, NodeStates{}, ne.NeighbourStates("x"))
+
+	all := ne.NodesWithinRangeOfIncl("2", 2)
+	assert.Equal(t, map[NodeState]int{"water": 2, "grass": 2}, ne.StateHistogram(all))
+	assert.Equal(t, 2, ne.CountInState(all, "water"))
+	assert.Equal(t, 0, ne.CountInState(all, "lava"))
+
+	nearest, distance := ne.NearestInState("5", "grass", 3)
+	assert.Equal(t, "6", nearest)
+	assert.Equal(t, 2, distance)
+	nearest, distance = ne.NearestInState("3", "water", 3)
+	assert.Equal(t, "3", nearest)
+	assert.Equal(t, 0, distance)
+	nearest, distance = ne.NearestInState("5", "grass", 1)
+	assert.Equal(t, "", nearest)
+	assert.Equal(t, -1, distance)
+}
@@ -2,7 +2,6 @@ package gwc
 
 import (
 	"math"
-	"math/rand"
 )
 
 // Builds a Node from the provided state function and neighbours.
@@ -15,13 +14,21 @@ func NewSuperpositionNode(id NodeID, super NodeSuperposition, neighbours ...Node
 	return NewNode(id, SuperpositionStateFn(super), neighbours...)
 }
 
+// Builds a Node from the provided state function, attributes and neighbours.
+func NewAttributeNode(id NodeID, fn NodeStateFn, attrs NodeAttributes, neighbours ...NodeID) Node {
+	if attrs == nil {
+		attrs = NodeAttributes{}
+	}
+	return &AttributeNode{BaseNode{id, neighbours, fn}, attrs}
+}
+
 type (
 	NodesMap map[NodeID]Node
 	Nodes    []Node
 	Node     interface {
 		ID() NodeID
 		Neighbours() NodeIDs
-		Collapse(*rand.Rand, NodeEnvironment) NodeState
+		Collapse(Random, NodeEnvironment) NodeState
 	}
 
 	NodeIDs []NodeID
@@ -29,7 +36,7 @@ type (
 
 	NodeProbability = float64
 	NodeState       = interface{}
-	NodeStateFn     = func(*rand.Rand, NodeEnvironment) NodeState
+	NodeStateFn     = func(Random, NodeEnvironment) NodeState
 )
 
 // BaseNode can be used as base for a more concrete struct, which implements a concrete Collapse() method.
@@ -47,73 +54,63 @@ func (n *BaseNode) Neighbours() NodeIDs {
 	return n.neighbours
 }
 
-func (n *BaseNode) Collapse(rnd *rand.Rand, env NodeEnvironment) NodeState {
+func (n *BaseNode) Collapse(rnd Random, env NodeEnvironment) NodeState {
 	if n.fn != nil {
 		return n.fn(rnd, env)
 	}
 	return nil
 }
 
-// Applies a logical AND to the two index lists and returns the product.
-func (ids NodeIDs) And(other NodeIDs) NodeIDs {
-	xs := NodeIDs{}
-	for _, a := range ids {
-		for _, b := range other {
-			if a == b {
-				xs = append(xs, a)
-			}
+// AttributedNode is a Node carrying additional attributes, e.g. from an imported graph.
+type AttributedNode interface {
+	Node
+	Attributes() NodeAttributes
+}
+
+type NodeAttributes = map[string]string
+
+// AttributeNode is a BaseNode with attributes.
+type AttributeNode struct {
+	BaseNode
+	attrs NodeAttributes
+}
+
+func (n *AttributeNode) Attributes() NodeAttributes {
+	return n.attrs
+}
+
+// Returns whether the list contains the ID.
+func (ids NodeIDs) Contains(id NodeID) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
 		}
 	}
-	return xs
+	return false
+}
+
+// Applies a logical AND to the two index lists and returns the product.
+func (ids NodeIDs) And(other NodeIDs) NodeIDs {
+	return ids.Set().Intersect(other.Set()).IDs()
 }
 
 // Applies a logical OR to the two index lists and returns the product.
 func (ids NodeIDs) Or(other NodeIDs) NodeIDs {
-	xs := NodeIDs{}
-	xs = append(xs, ids...)
-Outer:
-	for _, b := range other {
-		for _, x := range xs {
-			if b == x {
-				continue Outer
-			}
-		}
-		xs = append(xs, b)
-	}
-	return xs
+	return ids.Set().Union(other.Set()).IDs()
 }
 
 // Applies a logical XOR to the two index lists and returns the product.
 func (ids NodeIDs) Xor(other NodeIDs) NodeIDs {
-	xs := NodeIDs{}
-Outer:
-	for _, a := range ids {
-		for _, b := range other {
-			if a == b {
-				continue Outer
-			}
-		}
-		xs = append(xs, a)
-	}
-Outer2:
-	for _, b := range other {
-		for _, a := range ids {
-			if a == b {
-				continue Outer2
-			}
-		}
-		xs = append(xs, b)
-	}
-	return xs
+	return ids.Set().SymmetricDifference(other.Set()).IDs()
 }
 
 type (
-	NodeSuperpositionFn = func(*rand.Rand, NodeEnvironment) (NodeProbability, NodeState)
+	NodeSuperpositionFn = func(Random, NodeEnvironment) (NodeProbability, NodeState)
 	NodeSuperposition   = []NodeSuperpositionFn
 )
 
 func SuperpositionStateFn(super NodeSuperposition) NodeStateFn {
-	return func(rnd *rand.Rand, env NodeEnvironment) NodeState {
+	return func(rnd Random, env NodeEnvironment) NodeState {
 		// Stop early when the Node's superposition is empty.
 		num := len(super)
 		if num == 0 {
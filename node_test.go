@@ -44,7 +44,7 @@ func newLinearNodes(super ...NodeSuperpositionFn) Nodes {
 	return nodes
 }
 
-func collapse(rnd *rand.Rand, ne *NodeEnvironment) NodeState {
+func collapse(rnd Random, ne *NodeEnvironment) NodeState {
 	return ne.Nodes[0].Collapse(rnd, *ne)
 }
 
@@ -57,7 +57,7 @@ func Test_Collapse(t *testing.T) {
 
 	// This superposition's only function always yields a nil state.
 	nil_super_ne := newDefaultTestNodesEnvironment(
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 0, nil
 		},
 	)
@@ -66,7 +66,7 @@ func Test_Collapse(t *testing.T) {
 	// This superposition only has a single non-probable state, resulting in it still collapsing into that state.
 	non_nil_state := "non_nil_state"
 	non_probable_super_ne := newDefaultTestNodesEnvironment(
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 0, non_nil_state
 		},
 	)
@@ -76,13 +76,13 @@ func Test_Collapse(t *testing.T) {
 	non_nil_state_2 := "non_nil_state_2"
 	non_nil_state_3 := "non_nil_state_3"
 	multi_non_probable_super_ne := newDefaultTestNodesEnvironment(
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 0, non_nil_state
 		},
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 0, non_nil_state_2
 		},
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 0, non_nil_state_3
 		},
 	)
@@ -90,16 +90,16 @@ func Test_Collapse(t *testing.T) {
 
 	// This superposition has many non-probable states and only one probable state.
 	non_probable_nil_super_ne := newDefaultTestNodesEnvironment(
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 1, non_nil_state
 		},
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 0, nil
 		},
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 0, nil
 		},
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 0, nil
 		},
 	)
@@ -108,7 +108,7 @@ func Test_Collapse(t *testing.T) {
 
 func Test_NewNodes(t *testing.T) {
 	new_node := NewSuperpositionNode("A", NodeSuperposition{
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 1, "non-nil-value"
 		},
 	})
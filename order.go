@@ -1,14 +1,12 @@
 package gwc
 
-import (
-	"math/rand"
-)
-
 // CollapseOrderFn is a function that takes the current NodeEnvironment and returns the NodeID of the next Node to be collapsed.
-type CollapseOrderFn func(*rand.Rand, NodeEnvironment) NodeID
+// The CollapseOrderFns of this package keep the state they need across the steps of a collapse within the collapsed environment,
+// so that they can be reused and shared between concurrent collapses.
+type CollapseOrderFn func(Random, NodeEnvironment) NodeID
 
 // Collapses the Nodes in totally random order.
-var RandomCollapseOrder CollapseOrderFn = func(rnd *rand.Rand, env NodeEnvironment) NodeID {
+var RandomCollapseOrder CollapseOrderFn = func(rnd Random, env NodeEnvironment) NodeID {
 	for _, idx := range rnd.Perm(len(env.Nodes)) {
 		id := env.GetID(idx)
 		if _, collapsed := env.CollapsedMap[id]; collapsed == false {
@@ -19,10 +17,10 @@ var RandomCollapseOrder CollapseOrderFn = func(rnd *rand.Rand, env NodeEnvironme
 }
 
 // Collapses the Nodes by choosing a random Node and then continuining with a random neighbour of the latest Node until running out of neighbours.
-var RandomStreakCollapseOrder CollapseOrderFn = func(rnd *rand.Rand, env NodeEnvironment) NodeID {
+var RandomStreakCollapseOrder CollapseOrderFn = func(rnd Random, env NodeEnvironment) NodeID {
 	// If this is not the first run, search for uncollapsed neighbours of the current node.
 	if env.Current != "" {
-		neighbours := env.NodesMap[env.Current].Neighbours()
+		neighbours := env.Neighbours(env.Current)
 		for _, idx := range rnd.Perm(len(neighbours)) {
 			id := neighbours[idx]
 			if _, collapsed := env.CollapsedMap[id]; collapsed == false {
@@ -37,36 +35,384 @@ var RandomStreakCollapseOrder CollapseOrderFn = func(rnd *rand.Rand, env NodeEnv
 	return ""
 }
 
-// Collapses the Nodes in ascending order.
-var AscendingCollapseOrder CollapseOrderFn = func(rnd *rand.Rand, env NodeEnvironment) NodeID {
-	if len(env.CollapsedMap) < len(env.Nodes) {
-		idx := len(env.CollapsedMap)
-		node := env.Nodes[idx]
-		if node != nil {
-			return node.ID()
+var ascendingKey, descendingKey = &orderKey{"ascending"}, &orderKey{"descending"}
+
+// Collapses the Nodes in ascending order. Nodes that have already been collapsed, e.g. by SetState(), are skipped.
+var AscendingCollapseOrder CollapseOrderFn = func(rnd Random, env NodeEnvironment) NodeID {
+	state := env.orderState(ascendingKey, func() interface{} { return &cursorState{} }).(*cursorState)
+	if state.tracker.reset(env) {
+		state.pos = 0
+	}
+	// All Nodes before the cursor are collapsed, so that each step takes constant time on average.
+	for ; state.pos < len(env.Nodes); state.pos++ {
+		if id := env.GetID(state.pos); isCollapsible(env, id) {
+			return id
 		}
 	}
 	return ""
 }
 
-// Collapses the Nodes in descending order.
-var DescendingCollapseOrder CollapseOrderFn = func(rnd *rand.Rand, env NodeEnvironment) NodeID {
-	if len(env.CollapsedMap) < len(env.Nodes) {
-		idx := len(env.Nodes) - len(env.CollapsedMap) - 1
-		node := env.Nodes[idx]
-		if node != nil {
-			return node.ID()
+// Collapses the Nodes in descending order. Nodes that have already been collapsed are skipped.
+var DescendingCollapseOrder CollapseOrderFn = func(rnd Random, env NodeEnvironment) NodeID {
+	state := env.orderState(descendingKey, func() interface{} { return &cursorState{} }).(*cursorState)
+	if state.tracker.reset(env) {
+		state.pos = 0
+	}
+	for ; state.pos < len(env.Nodes); state.pos++ {
+		if id := env.GetID(len(env.Nodes) - 1 - state.pos); isCollapsible(env, id) {
+			return id
 		}
 	}
 	return ""
 }
 
 // Produces a CollapseOrderFn that collapses the Nodes in the provided order.
+// Unknown, duplicate and already collapsed IDs are skipped. Once the order is exhausted, "" is returned,
+// even if there are uncollapsed Nodes left. Use ValidatedFixedCollapseOrder to detect such problems upfront.
 func FixedCollapseOrder(order []NodeID) CollapseOrderFn {
-	return func(rnd *rand.Rand, env NodeEnvironment) NodeID {
-		if len(env.CollapsedMap) < len(env.Nodes) {
-			return order[len(env.CollapsedMap)]
+	key := &orderKey{"fixed"}
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		state := env.orderState(key, func() interface{} { return &cursorState{} }).(*cursorState)
+		if state.tracker.reset(env) {
+			state.pos = 0
+		}
+		for ; state.pos < len(order); state.pos++ {
+			if id := order[state.pos]; isCollapsible(env, id) {
+				return id
+			}
+		}
+		return ""
+	}
+}
+
+// cursorState is the state of CollapseOrderFns that walk through a list of Nodes, all of which are collapsed before pos.
+type cursorState struct {
+	tracker orderTracker
+	pos     int
+}
+
+// Validates the order against the Nodes and produces a FixedCollapseOrder from it.
+// Unknown and duplicate IDs within the order are always reported as error. Nodes missing from the order are
+// collapsed by the fallback order after the fixed order is exhausted, or reported as error if fallback is nil.
+func ValidatedFixedCollapseOrder(order NodeIDs, nodes Nodes, fallback CollapseOrderFn) (CollapseOrderFn, error) {
+	if err := ValidateCollapseOrder(order, nodes); err != nil {
+		errs := err.(ValidationErrors)
+		if fallback != nil {
+			errs = errs.Without(UnorderedNode)
+		}
+		if len(errs) > 0 {
+			return nil, errs
+		}
+	}
+	if fallback == nil {
+		return FixedCollapseOrder(order), nil
+	}
+	return ChainCollapseOrder(FixedCollapseOrder(order), fallback), nil
+}
+
+// Validates that the Nodes form a directed acyclic graph and produces a CollapseOrderFn, which only collapses a Node
+// after all Nodes listing it as their neighbour have been collapsed, i.e. edges point from a dependency to its dependents.
+// Among the Nodes whose dependencies are all collapsed, a random one is chosen.
+// Returns an error for each Node that is part of a cycle.
+func TopologicalCollapseOrder(nodes Nodes) (CollapseOrderFn, error) {
+	env := NewNodeEnvironment(nodes)
+	pending := map[NodeID]int{}
+	for _, node := range nodes {
+		pending[node.ID()] = len(env.Predecessors(node.ID()))
+	}
+	ready := NodeIDs{}
+	for _, node := range nodes {
+		if pending[node.ID()] == 0 {
+			ready = append(ready, node.ID())
+		}
+	}
+	for i := 0; i < len(ready); i++ {
+		for _, ni := range env.Neighbours(ready[i]) {
+			if _, exists := pending[ni]; exists {
+				pending[ni]--
+				if pending[ni] == 0 {
+					ready = append(ready, ni)
+				}
+			}
+		}
+	}
+	if len(ready) < len(nodes) {
+		errs := ValidationErrors{}
+		for idx, node := range nodes {
+			if pending[node.ID()] > 0 {
+				errs = append(errs, ValidationError{Kind: Cycle, Index: idx, ID: node.ID()})
+			}
+		}
+		return nil, errs
+	}
+
+	key := &orderKey{"topological"}
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		state := env.orderState(key, func() interface{} { return &topologicalState{} }).(*topologicalState)
+		previously := state.tracker.collapsed
+		if state.tracker.reset(env) || len(env.CollapsedMap) != previously+1 || isCollapsible(env, state.last) {
+			// Count the uncollapsed dependencies of every uncollapsed Node.
+			state.waiting = map[NodeID]int{}
+			state.queue = NodeIDs{}
+			for _, node := range env.Nodes {
+				id := node.ID()
+				if !isCollapsible(env, id) {
+					continue
+				}
+				for _, pi := range env.Predecessors(id) {
+					if isCollapsible(env, pi) {
+						state.waiting[id]++
+					}
+				}
+				if state.waiting[id] == 0 {
+					state.queue = append(state.queue, id)
+				}
+			}
+		} else {
+			// Only the Node returned last has been collapsed, which may have been the last dependency of its neighbours.
+			for _, ni := range env.Neighbours(state.last) {
+				if _, exists := state.waiting[ni]; exists && isCollapsible(env, ni) {
+					state.waiting[ni]--
+					if state.waiting[ni] == 0 {
+						state.queue = append(state.queue, ni)
+					}
+				}
+			}
+		}
+
+		for len(state.queue) > 0 {
+			idx := rnd.Intn(len(state.queue))
+			id := state.queue[idx]
+			state.queue[idx] = state.queue[len(state.queue)-1]
+			state.queue = state.queue[:len(state.queue)-1]
+			if isCollapsible(env, id) {
+				state.last = id
+				return id
+			}
 		}
+		state.last = ""
 		return ""
+	}, nil
+}
+
+type topologicalState struct {
+	tracker orderTracker
+	waiting map[NodeID]int
+	queue   NodeIDs
+	last    NodeID
+}
+
+// orderRun keeps the state of stateful CollapseOrderFns during a single collapse, so that the CollapseOrderFns themselves
+// don't have any state and can be shared between concurrent collapses.
+type orderRun struct {
+	states map[*orderKey]interface{}
+}
+
+// orderKey identifies the state of a CollapseOrderFn within an orderRun. Every stateful CollapseOrderFn allocates its own key.
+type orderKey struct {
+	name string
+}
+
+func newOrderRun() *orderRun {
+	return &orderRun{states: map[*orderKey]interface{}{}}
+}
+
+// Returns the state of the CollapseOrderFn with the key within the environment's current collapse, which is created by init first if necessary.
+// Outside of a collapse, e.g. if the CollapseOrderFn is called directly, a new state is created on every call.
+func (ne *NodeEnvironment) orderState(key *orderKey, init func() interface{}) interface{} {
+	if ne.orders == nil {
+		return init()
+	}
+	state, exists := ne.orders.states[key]
+	if !exists {
+		state = init()
+		ne.orders.states[key] = state
+	}
+	return state
+}
+
+// orderTracker detects when a stateful CollapseOrderFn has to reset its state within a collapse,
+// because the graph has changed or Nodes have been uncollapsed since its previous call.
+type orderTracker struct {
+	collapsed int
+	version   int
+	started   bool
+}
+
+// Returns true on the first call, or if the environment has changed otherwise than by collapsing Nodes since the previous call.
+func (t *orderTracker) reset(env NodeEnvironment) bool {
+	changed := !t.started || len(env.CollapsedMap) < t.collapsed || env.version != t.version
+	t.collapsed, t.version, t.started = len(env.CollapsedMap), env.version, true
+	return changed
+}
+
+// Returns a random uncollapsed Node that isn't excluded, or "" if there is none.
+func randomUncollapsed(rnd Random, env NodeEnvironment, excluded func(NodeID) bool) NodeID {
+	for _, idx := range rnd.Perm(len(env.Nodes)) {
+		id := env.GetID(idx)
+		if _, collapsed := env.CollapsedMap[id]; !collapsed && !excluded(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+// Produces a CollapseOrderFn that collapses the Nodes in breadth-first rings around the seeds, in random order within each ring.
+// If no seed is provided or some Nodes can't be reached from the seeds, a random uncollapsed Node is used as new seed.
+func WavefrontCollapseOrder(seeds ...NodeID) CollapseOrderFn {
+	key := &orderKey{"wavefront"}
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		state := env.orderState(key, func() interface{} { return &wavefrontState{} }).(*wavefrontState)
+		if state.tracker.reset(env) {
+			state.visited = NewNodeIDSet()
+			state.ring = NodeIDs{}
+			for _, id := range seeds {
+				if _, exists := env.NodesMap[id]; exists && !state.visited.Contains(id) {
+					state.visited.Add(id)
+					state.ring = append(state.ring, id)
+				}
+			}
+			shuffleIDs(rnd, state.ring)
+			state.pos = 0
+		}
+
+		for {
+			for state.pos < len(state.ring) {
+				id := state.ring[state.pos]
+				state.pos++
+				if _, collapsed := env.CollapsedMap[id]; !collapsed {
+					return id
+				}
+			}
+
+			// The ring is exhausted, so continue with the next one.
+			next := NodeIDs{}
+			for _, id := range state.ring {
+				for _, ni := range env.Neighbours(id) {
+					if _, exists := env.NodesMap[ni]; exists && !state.visited.Contains(ni) {
+						state.visited.Add(ni)
+						next = append(next, ni)
+					}
+				}
+			}
+			if len(next) == 0 {
+				seed := randomUncollapsed(rnd, env, state.visited.Contains)
+				if seed == "" {
+					return ""
+				}
+				state.visited.Add(seed)
+				next = NodeIDs{seed}
+			}
+			shuffleIDs(rnd, next)
+			state.ring, state.pos = next, 0
+		}
+	}
+}
+
+type wavefrontState struct {
+	tracker orderTracker
+	ring    NodeIDs
+	pos     int
+	visited *NodeIDSet
+}
+
+// Produces a CollapseOrderFn that grows one region around each of the seeds at the same time, resulting in Voronoi-like regions.
+// In each step, the next region in turn collapses a random Node of its frontier and adds its unclaimed neighbours to the frontier.
+// Nodes that can't be reached from any seed are grown from new random seeds.
+func GrowthCollapseOrder(seeds ...NodeID) CollapseOrderFn {
+	key := &orderKey{"growth"}
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		state := env.orderState(key, func() interface{} { return &growthState{} }).(*growthState)
+		if state.tracker.reset(env) {
+			state.claimed = NewNodeIDSet()
+			state.frontiers = []NodeIDs{}
+			state.turn = 0
+			for _, id := range seeds {
+				if _, exists := env.NodesMap[id]; exists && !state.claimed.Contains(id) {
+					state.claimed.Add(id)
+					state.frontiers = append(state.frontiers, NodeIDs{id})
+				}
+			}
+		}
+		frontiers, claimed := state.frontiers, state.claimed
+
+		for tries := 0; tries < len(frontiers); tries++ {
+			i := state.turn % len(frontiers)
+			state.turn++
+
+			for len(frontiers[i]) > 0 {
+				// Pick a random Node of the frontier and replace it with the frontier's last Node.
+				idx := rnd.Intn(len(frontiers[i]))
+				id := frontiers[i][idx]
+				last := len(frontiers[i]) - 1
+				frontiers[i][idx] = frontiers[i][last]
+				frontiers[i] = frontiers[i][:last]
+
+				for _, ni := range env.Neighbours(id) {
+					if _, exists := env.NodesMap[ni]; exists && !claimed.Contains(ni) {
+						claimed.Add(ni)
+						frontiers[i] = append(frontiers[i], ni)
+					}
+				}
+				if _, collapsed := env.CollapsedMap[id]; !collapsed {
+					return id
+				}
+			}
+		}
+
+		// All frontiers are exhausted, so grow a new region from a random seed.
+		seed := randomUncollapsed(rnd, env, claimed.Contains)
+		if seed == "" {
+			return ""
+		}
+		claimed.Add(seed)
+		frontier := NodeIDs{}
+		for _, ni := range env.Neighbours(seed) {
+			if _, exists := env.NodesMap[ni]; exists && !claimed.Contains(ni) {
+				claimed.Add(ni)
+				frontier = append(frontier, ni)
+			}
+		}
+		state.frontiers = append(frontiers, frontier)
+		state.turn = len(state.frontiers)
+		return seed
+	}
+}
+
+type growthState struct {
+	tracker   orderTracker
+	frontiers []NodeIDs
+	claimed   *NodeIDSet
+	turn      int
+}
+
+// Produces a GrowthCollapseOrder with the given number of random seeds.
+func RandomGrowthCollapseOrder(regions int) CollapseOrderFn {
+	key := &orderKey{"random growth"}
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		state := env.orderState(key, func() interface{} { return &randomGrowthState{} }).(*randomGrowthState)
+		if state.tracker.reset(env) {
+			seeds := NodeIDs{}
+			for _, idx := range rnd.Perm(len(env.Nodes)) {
+				if len(seeds) >= regions {
+					break
+				}
+				seeds = append(seeds, env.GetID(idx))
+			}
+			state.order = GrowthCollapseOrder(seeds...)
+		}
+		return state.order(rnd, env)
+	}
+}
+
+type randomGrowthState struct {
+	tracker orderTracker
+	order   CollapseOrderFn
+}
+
+func shuffleIDs(rnd Random, ids NodeIDs) {
+	for i := len(ids) - 1; i > 0; i-- {
+		j := rnd.Intn(i + 1)
+		ids[i], ids[j] = ids[j], ids[i]
 	}
 }
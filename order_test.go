@@ -2,6 +2,8 @@ package gwc
 
 import (
 	"math/rand"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,16 +11,16 @@ import (
 
 func newAbcdNodeSuperposition() NodeSuperposition {
 	return NodeSuperposition{
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 5, "A"
 		},
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 2, "B"
 		},
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 3, "C"
 		},
-		func(_ *rand.Rand, _ NodeEnvironment) (NodeProbability, NodeState) {
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
 			return 0, "D"
 		},
 	}
@@ -107,3 +109,257 @@ func Test_LinearNodes(t *testing.T) {
 		assert.EqualValues(t, expected[i], collapsed.Collapsed())
 	}
 }
+
+func Test_UndirectedCollapse(t *testing.T) {
+	// The chain only links forwards: 0 -> 1 -> 2 -> 3
+	nodes := Nodes{
+		NewNode("0", nil, "1"),
+		NewNode("1", nil, "2"),
+		NewNode("2", nil, "3"),
+		NewNode("3", nil),
+	}
+
+	sim := New(rand.New(rand.NewSource(7)), RandomStreakCollapseOrder, nodes, WithUndirectedEdges(), WithValidation())
+	assert.NoError(t, sim.Err())
+
+	// As the streak may walk against the edges' direction, every Node is adjacent to a previously collapsed one.
+	collapsed := sim.Collapse()
+	ids := collapsed.Collapsed()
+	assert.Len(t, ids, 4)
+	assert.True(t, collapsed.Undirected)
+	for i := 1; i < len(ids); i++ {
+		prev := collapsed.NodesWithinRangeOfIncl(ids[i], 1)
+		assert.NotEmpty(t, prev.And(ids[:i]))
+	}
+}
+
+func Test_ConcurrentCollapseOrders(t *testing.T) {
+	nodes := newDefaultTestNodes()
+	orders := []CollapseOrderFn{
+		WavefrontCollapseOrder("6"),
+		GrowthCollapseOrder("0", "6"),
+		RandomGrowthCollapseOrder(2),
+		PriorityCollapseOrder(func(id NodeID, env NodeEnvironment) float64 { return float64(len(env.NeighbourStates(id))) }),
+		ChainCollapseOrder(RestrictedCollapseOrder(WavefrontCollapseOrder("4"), NodeIDs{"2", "4", "5"}), WavefrontCollapseOrder("0")),
+		ChainCollapseOrder(FixedCollapseOrder(NodeIDs{"3", "1"}), DescendingCollapseOrder),
+	}
+	for _, order := range orders {
+		expected := make([]NodeIDs, 8)
+		for i := range expected {
+			collapsed := New(rand.New(rand.NewSource(int64(i))), order, nodes).Collapse()
+			expected[i] = collapsed.Collapsed()
+		}
+
+		// Sharing the order between concurrent collapses doesn't change their results.
+		actual := make([]NodeIDs, len(expected))
+		var wg sync.WaitGroup
+		for i := range actual {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				collapsed := New(rand.New(rand.NewSource(int64(i))), order, nodes).Collapse()
+				actual[i] = collapsed.Collapsed()
+			}(i)
+		}
+		wg.Wait()
+		assert.EqualValues(t, expected, actual)
+	}
+}
+
+func Test_PinnedNodesKeepOrder(t *testing.T) {
+	sim := New(rand.New(rand.NewSource(1)), AscendingCollapseOrder, newDefaultTestNodes())
+	pinned := func() NodeEnvironment {
+		env := sim.Environment()
+		env.SetState("5", "a")
+		env.SetState("6", "a")
+		return env
+	}
+
+	// Nodes collapsed out of order are skipped without disturbing the order of the remaining Nodes.
+	orders := map[string]CollapseOrderFn{
+		"ascending":  AscendingCollapseOrder,
+		"descending": DescendingCollapseOrder,
+		"fixed":      FixedCollapseOrder(NodeIDs{"6", "3", "5", "1", "0", "4", "2"}),
+	}
+	expected := map[string]NodeIDs{
+		"ascending":  {"5", "6", "0", "1", "2", "3", "4"},
+		"descending": {"5", "6", "4", "3", "2", "1", "0"},
+		"fixed":      {"5", "6", "3", "1", "0", "4", "2"},
+	}
+	for name, order := range orders {
+		sim := New(rand.New(rand.NewSource(1)), order, newDefaultTestNodes())
+		collapsed := sim.CollapseEnvironment(pinned())
+		assert.EqualValues(t, expected[name], collapsed.Collapsed(), name)
+	}
+}
+
+func Test_WavefrontCollapseOrder(t *testing.T) {
+	nodes := newDefaultTestNodes()
+	order := WavefrontCollapseOrder("6")
+
+	// The order can be reused and collapses the rings around the seed one after another.
+	for _, seed := range []int64{1, 2, 3} {
+		collapsed := New(rand.New(rand.NewSource(seed)), order, nodes).Collapse()
+		ids := collapsed.Collapsed()
+
+		assert.Len(t, ids, 7)
+		assert.Equal(t, "6", ids[0])
+		assert.Equal(t, "4", ids[1])
+		assert.ElementsMatch(t, NodeIDs{"2", "5"}, ids[2:4])
+		assert.ElementsMatch(t, NodeIDs{"0", "1", "3"}, ids[4:])
+	}
+
+	// Without seeds or with disconnected Nodes, random seeds are used.
+	disconnected := append(newLinearNodes(), NewNode("x", nil))
+	collapsed := New(rand.New(rand.NewSource(1)), WavefrontCollapseOrder("1"), disconnected).Collapse()
+	ids := collapsed.Collapsed()
+	assert.Equal(t, "1", ids[0])
+	assert.ElementsMatch(t, NodeIDs{"0", "2"}, ids[1:3])
+	assert.EqualValues(t, NodeIDs{"3", "x"}, ids[3:])
+	collapsed = New(rand.New(rand.NewSource(1)), WavefrontCollapseOrder(), disconnected).Collapse()
+	assert.Len(t, collapsed.Collapsed(), 5)
+}
+
+func Test_GrowthCollapseOrder(t *testing.T) {
+	// The graph is a line of 9 Nodes: 0 - 1 - ... - 8
+	nodes := Nodes{}
+	for i := 0; i < 9; i++ {
+		neighbours := NodeIDs{}
+		if i > 0 {
+			neighbours = append(neighbours, strconv.Itoa(i-1))
+		}
+		if i < 8 {
+			neighbours = append(neighbours, strconv.Itoa(i+1))
+		}
+		nodes = append(nodes, NewNode(strconv.Itoa(i), nil, neighbours...))
+	}
+
+	// Both regions grow in turns, until they meet in the middle.
+	collapsed := New(rand.New(rand.NewSource(1)), GrowthCollapseOrder("0", "8"), nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"0", "8", "1", "7", "2", "6", "3", "5", "4"}, collapsed.Collapsed())
+
+	collapsed = New(rand.New(rand.NewSource(1)), RandomGrowthCollapseOrder(3), nodes).Collapse()
+	assert.Len(t, collapsed.Collapsed(), 9)
+
+	disconnected := append(newLinearNodes(), NewNode("x", nil))
+	collapsed = New(rand.New(rand.NewSource(1)), GrowthCollapseOrder("0"), disconnected).Collapse()
+	assert.EqualValues(t, NodeIDs{"0", "1", "2", "3", "x"}, collapsed.Collapsed())
+}
+
+func Test_FixedCollapseOrder(t *testing.T) {
+	nodes := newDefaultTestNodes()
+
+	// Unknown and duplicate IDs are skipped and short orders don't panic.
+	order := FixedCollapseOrder([]NodeID{"3", "x", "1", "3", "6"})
+	collapsed := New(rand.New(rand.NewSource(1)), order, nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"3", "1", "6"}, collapsed.Collapsed())
+
+	_, err := ValidatedFixedCollapseOrder(NodeIDs{"3", "x", "1", "3", "6"}, nodes, RandomCollapseOrder)
+	assert.EqualValues(t, ValidationErrors{
+		{Kind: UnknownOrderedID, Index: 1, ID: "x"},
+		{Kind: DuplicateOrderedID, Index: 3, ID: "3"},
+	}, err)
+
+	_, err = ValidatedFixedCollapseOrder(NodeIDs{"3", "1", "6"}, nodes, nil)
+	assert.Error(t, err)
+	assert.Len(t, err, 4)
+
+	order, err = ValidatedFixedCollapseOrder(NodeIDs{"3", "1", "6"}, nodes, DescendingCollapseOrder)
+	assert.NoError(t, err)
+	collapsed = New(rand.New(rand.NewSource(1)), order, nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"3", "1", "6", "5", "4", "2", "0"}, collapsed.Collapsed())
+
+	order, err = ValidatedFixedCollapseOrder(NodeIDs{"6", "5", "4", "3", "2", "1", "0"}, nodes, nil)
+	assert.NoError(t, err)
+	collapsed = New(rand.New(rand.NewSource(1)), order, nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"6", "5", "4", "3", "2", "1", "0"}, collapsed.Collapsed())
+}
+
+func Test_TopologicalCollapseOrder(t *testing.T) {
+	// The graph has the following form, with edges pointing downwards:
+	//     a
+	//    / \
+	//   b   c
+	//    \ / \
+	//     d   e
+	nodes := Nodes{
+		NewNode("d", nil),
+		NewNode("c", nil, "d", "e"),
+		NewNode("a", nil, "b", "c"),
+		NewNode("e", nil),
+		NewNode("b", nil, "d"),
+	}
+	order, err := TopologicalCollapseOrder(nodes)
+	assert.NoError(t, err)
+
+	for _, seed := range []int64{1, 2, 3, 4} {
+		collapsed := New(rand.New(rand.NewSource(seed)), order, nodes).Collapse()
+		ids := collapsed.Collapsed()
+		assert.Len(t, ids, 5)
+		for _, node := range nodes {
+			for _, ni := range node.Neighbours() {
+				assert.Less(t, collapsed.CollapsedMap[node.ID()], collapsed.CollapsedMap[ni])
+			}
+		}
+	}
+
+	_, err = TopologicalCollapseOrder(Nodes{
+		NewNode("a", nil, "b"),
+		NewNode("b", nil, "c"),
+		NewNode("c", nil, "b"),
+	})
+	assert.EqualValues(t, ValidationErrors{
+		{Kind: Cycle, Index: 1, ID: "b"},
+		{Kind: Cycle, Index: 2, ID: "c"},
+	}, err)
+}
+
+func Test_CollapseSubset(t *testing.T) {
+	super := newAbcdNodeSuperposition()
+	sim := New(rand.New(rand.NewSource(42)), DescendingCollapseOrder, newDefaultTestNodes(super...))
+
+	// Collapse the region around Node 6 first, then the rest.
+	env := sim.Environment()
+	env = sim.CollapseSubset(env, env.NodesWithinRangeOfIncl("6", 1))
+	assert.EqualValues(t, NodeIDs{"6", "4"}, env.Collapsed())
+	assert.Nil(t, env.StatesMap["5"])
+
+	env = sim.CollapseSubset(env, NodeIDs{"0", "4", "5"})
+	assert.EqualValues(t, NodeIDs{"6", "4", "5", "0"}, env.Collapsed())
+
+	state := env.StatesMap["6"]
+	env = sim.CollapseSubset(env, env.Query().Uncollapsed().IDs())
+	assert.EqualValues(t, NodeIDs{"6", "4", "5", "0", "3", "2", "1"}, env.Collapsed())
+	assert.Equal(t, state, env.StatesMap["6"])
+	assert.Len(t, env.StatesMap, 7)
+}
+
+func Test_Reroll(t *testing.T) {
+	sim := New(rand.New(rand.NewSource(42)), AscendingCollapseOrder, newDefaultTestNodes(newAbcdNodeSuperposition()...))
+	env := sim.Collapse()
+	states := env.States()
+
+	rerolled := sim.Reroll(env, NodeIDs{"6"}, 1)
+	assert.EqualValues(t, NodeIDs{"0", "1", "2", "3", "5", "4", "6"}, rerolled.Collapsed())
+	for _, id := range (NodeIDs{"0", "1", "2", "3", "5"}) {
+		assert.Equal(t, env.StatesMap[id], rerolled.StatesMap[id])
+	}
+	// The original environment is left unchanged.
+	assert.EqualValues(t, NodeIDs{"0", "1", "2", "3", "4", "5", "6"}, env.Collapsed())
+	assert.EqualValues(t, states, env.States())
+
+	// Rerolled Nodes see the fixed states around them: Node 6 copies the state of its only neighbour.
+	copying := NewSuperpositionNode("6", NodeSuperposition{
+		func(_ Random, env NodeEnvironment) (NodeProbability, NodeState) {
+			return 1, env.StatesMap["4"]
+		},
+	}, "4")
+	nodes := newDefaultTestNodes(newAbcdNodeSuperposition()...)
+	nodes[6] = copying
+	sim = New(rand.New(rand.NewSource(42)), DescendingCollapseOrder, nodes)
+	env = sim.Collapse()
+	env.StatesMap["4"] = "X"
+	rerolled = sim.Reroll(env, NodeIDs{"6", "unknown"}, 0)
+	assert.Equal(t, "X", rerolled.StatesMap["6"])
+	assert.EqualValues(t, NodeIDs{"5", "4", "3", "2", "1", "0", "6"}, rerolled.Collapsed())
+}
@@ -0,0 +1,150 @@
+package gwc
+
+import (
+	"container/heap"
+)
+
+// NodeScoreFn rates how urgently a Node should be collapsed. Nodes with higher scores are collapsed first.
+type NodeScoreFn = func(NodeID, NodeEnvironment) float64
+
+// Produces a CollapseOrderFn that always collapses the uncollapsed Node with the highest score, preferring earlier Nodes on ties.
+// After each collapse, only the neighbours and predecessors of the collapsed Node are scored again,
+// thus the score of a Node may only depend on its direct neighbourhood.
+func PriorityCollapseOrder(score NodeScoreFn) CollapseOrderFn {
+	return PriorityCollapseOrderWithin(score, 1)
+}
+
+// Like PriorityCollapseOrder, but scores all Nodes within the given range of the collapsed Node again,
+// so that the score of a Node may depend on its neighbourhood up to that range.
+func PriorityCollapseOrderWithin(score NodeScoreFn, depth uint) CollapseOrderFn {
+	key := &orderKey{"priority"}
+	return func(rnd Random, env NodeEnvironment) NodeID {
+		state := env.orderState(key, func() interface{} { return &priorityState{} }).(*priorityState)
+		collapsed := len(env.CollapsedMap)
+		previously := state.tracker.collapsed
+		if state.tracker.reset(env) {
+			state.queue = &priorityQueue{positions: map[NodeID]int{}}
+			for idx, node := range env.Nodes {
+				id := node.ID()
+				if _, done := env.CollapsedMap[id]; !done {
+					state.queue.items = append(state.queue.items, &priorityItem{id, idx, score(id, env)})
+					state.queue.positions[id] = len(state.queue.items) - 1
+				}
+			}
+			heap.Init(state.queue)
+		} else if _, done := env.CollapsedMap[state.last]; done && collapsed == previously+1 {
+			// Only the Node returned last has been collapsed, so only its neighbourhood needs to be updated.
+			state.queue.remove(state.last)
+			for _, id := range state.queue.affected(env, state.last, depth) {
+				state.queue.update(id, score(id, env))
+			}
+		} else if collapsed != previously {
+			// The environment has been changed otherwise, so all remaining Nodes are updated.
+			for _, item := range append([]*priorityItem{}, state.queue.items...) {
+				if _, done := env.CollapsedMap[item.id]; done {
+					state.queue.remove(item.id)
+				} else {
+					state.queue.update(item.id, score(item.id, env))
+				}
+			}
+		}
+
+		queue := state.queue
+		for queue.Len() > 0 {
+			top := queue.items[0]
+			if _, done := env.CollapsedMap[top.id]; !done {
+				state.last = top.id
+				return top.id
+			}
+			queue.remove(top.id)
+		}
+		state.last = ""
+		return ""
+	}
+}
+
+type priorityState struct {
+	tracker orderTracker
+	queue   *priorityQueue
+	last    NodeID
+}
+
+type priorityItem struct {
+	id    NodeID
+	index int
+	score float64
+}
+
+// priorityQueue is an indexed max-heap of the uncollapsed Nodes.
+type priorityQueue struct {
+	items     []*priorityItem
+	positions map[NodeID]int
+}
+
+func (q *priorityQueue) Len() int {
+	return len(q.items)
+}
+
+func (q *priorityQueue) Less(i, j int) bool {
+	if q.items[i].score != q.items[j].score {
+		return q.items[i].score > q.items[j].score
+	}
+	return q.items[i].index < q.items[j].index
+}
+
+func (q *priorityQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.positions[q.items[i].id] = i
+	q.positions[q.items[j].id] = j
+}
+
+func (q *priorityQueue) Push(x interface{}) {
+	item := x.(*priorityItem)
+	q.positions[item.id] = len(q.items)
+	q.items = append(q.items, item)
+}
+
+func (q *priorityQueue) Pop() interface{} {
+	last := len(q.items) - 1
+	item := q.items[last]
+	q.items = q.items[:last]
+	delete(q.positions, item.id)
+	return item
+}
+
+func (q *priorityQueue) remove(id NodeID) {
+	if pos, exists := q.positions[id]; exists {
+		heap.Remove(q, pos)
+	}
+}
+
+func (q *priorityQueue) update(id NodeID, score float64) {
+	if pos, exists := q.positions[id]; exists && q.items[pos].score != score {
+		q.items[pos].score = score
+		heap.Fix(q, pos)
+	}
+}
+
+// Returns the queued Nodes whose neighbourhood up to depth contains the given Node.
+func (q *priorityQueue) affected(env NodeEnvironment, id NodeID, depth uint) NodeIDs {
+	ids := NewNodeIDSet()
+	frontier := NodeIDs{id}
+	seen := NewNodeIDSet(id)
+	for d := uint(0); d < depth && len(frontier) > 0; d++ {
+		next := NodeIDs{}
+		for _, fi := range frontier {
+			adjacent := append(NodeIDs{}, env.Neighbours(fi)...)
+			for _, ni := range append(adjacent, env.Predecessors(fi)...) {
+				if !seen.Contains(ni) {
+					seen.Add(ni)
+					next = append(next, ni)
+					if _, queued := q.positions[ni]; queued {
+						ids.Add(ni)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return ids.IDs()
+}
@@ -0,0 +1,74 @@
+package gwc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PriorityCollapseOrder(t *testing.T) {
+	// Nodes with the most collapsed neighbours are collapsed first.
+	score := func(id NodeID, env NodeEnvironment) float64 {
+		return float64(len(env.NeighbourStates(id)))
+	}
+
+	nodes := newDefaultTestNodes()
+	collapsed := New(rand.New(rand.NewSource(1)), PriorityCollapseOrder(score), nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"0", "2", "1", "3", "4", "5", "6"}, collapsed.Collapsed())
+
+	// Nodes closest to the start are collapsed first.
+	closest := func(id NodeID, env NodeEnvironment) float64 {
+		return -float64(env.Distance("6", id))
+	}
+	collapsed = New(rand.New(rand.NewSource(1)), PriorityCollapseOrder(closest), nodes).Collapse()
+	assert.EqualValues(t, NodeIDs{"6", "4", "2", "5", "0", "1", "3"}, collapsed.Collapsed())
+}
+
+func Test_PriorityCollapseOrderWithin(t *testing.T) {
+	// The score depends on the states within 2 hops, which have to be updated incrementally.
+	score := func(id NodeID, env NodeEnvironment) float64 {
+		sum := 0.0
+		for _, ni := range env.NodesWithinRangeOfExcl(id, 2) {
+			if state, collapsed := env.StatesMap[ni]; collapsed {
+				sum += state.(float64) / float64(env.Distance(id, ni))
+			}
+		}
+		return sum
+	}
+	super := NodeSuperposition{
+		func(rnd Random, _ NodeEnvironment) (NodeProbability, NodeState) {
+			return 1, rnd.Float64()
+		},
+	}
+
+	// Compare against a naive order, which scores all Nodes in every step.
+	naive := func(_ Random, env NodeEnvironment) NodeID {
+		best, best_score := NodeID(""), 0.0
+		for _, node := range env.Nodes {
+			id := node.ID()
+			if _, collapsed := env.CollapsedMap[id]; collapsed {
+				continue
+			}
+			if s := score(id, env); best == "" || s > best_score {
+				best, best_score = id, s
+			}
+		}
+		return best
+	}
+
+	nodes := newDefaultTestNodes(super...)
+	expected := New(rand.New(rand.NewSource(5)), naive, nodes).Collapse()
+	collapsed := New(rand.New(rand.NewSource(5)), PriorityCollapseOrderWithin(score, 2), nodes).Collapse()
+	assert.EqualValues(t, expected.Collapsed(), collapsed.Collapsed())
+
+	// Changes made outside of the order are picked up as well.
+	order := PriorityCollapseOrder(func(id NodeID, env NodeEnvironment) float64 {
+		return float64(len(env.NeighbourStates(id)))
+	})
+	env := NewNodeEnvironment(newDefaultTestNodes())
+	assert.Equal(t, "0", order(nil, *env))
+	env.CollapsedMap["6"] = 0
+	env.CollapsedMap["5"] = 1
+	assert.Equal(t, "4", order(nil, *env))
+}
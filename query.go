@@ -0,0 +1,163 @@
+package gwc
+
+import "reflect"
+
+// Starts a query over all Nodes of the environment. The query's methods narrow down the result and can be chained:
+//
+//	env.Query().Within(id, 2).Collapsed().InState("water").IDs()
+func (ne *NodeEnvironment) Query() *NodeQuery {
+	return &NodeQuery{env: ne}
+}
+
+// NodeQuery selects Nodes of a NodeEnvironment. Results keep the order of the environment's Nodes,
+// unless the query has been restricted to a list of IDs, whose order is kept instead.
+type NodeQuery struct {
+	env     *NodeEnvironment
+	ids     *NodeIDSet
+	filters []NodeFilterFn
+}
+
+// Restricts the query to the provided IDs. Unknown IDs are ignored.
+func (q *NodeQuery) In(ids ...NodeID) *NodeQuery {
+	if q.ids == nil {
+		q.ids = NewNodeIDSet()
+		for _, id := range ids {
+			if _, exists := q.env.NodesMap[id]; exists {
+				q.ids.Add(id)
+			}
+		}
+		return q
+	}
+
+	q.ids = q.ids.Intersect(NewNodeIDSet(ids...))
+	return q
+}
+
+// Excludes the provided IDs from the query.
+func (q *NodeQuery) Except(ids ...NodeID) *NodeQuery {
+	drop := NewNodeIDSet(ids...)
+	return q.Where(func(id NodeID, _ NodeState) bool {
+		return !drop.Contains(id)
+	})
+}
+
+// Restricts the query to the Nodes within range of the given Node, including itself, ordered by distance.
+func (q *NodeQuery) Within(id NodeID, depth uint) *NodeQuery {
+	return q.In(q.env.NodesWithinRangeOfIncl(id, depth)...)
+}
+
+// Restricts the query to the neighbours of the given Node.
+func (q *NodeQuery) NeighboursOf(id NodeID) *NodeQuery {
+	return q.In(q.env.Neighbours(id)...)
+}
+
+// Restricts the query to collapsed Nodes.
+func (q *NodeQuery) Collapsed() *NodeQuery {
+	return q.Where(func(id NodeID, _ NodeState) bool {
+		_, collapsed := q.env.CollapsedMap[id]
+		return collapsed
+	})
+}
+
+// Restricts the query to uncollapsed Nodes.
+func (q *NodeQuery) Uncollapsed() *NodeQuery {
+	return q.Where(func(id NodeID, _ NodeState) bool {
+		_, collapsed := q.env.CollapsedMap[id]
+		return !collapsed
+	})
+}
+
+// Restricts the query to collapsed Nodes in one of the provided states.
+func (q *NodeQuery) InState(states ...NodeState) *NodeQuery {
+	return q.Collapsed().Where(func(_ NodeID, state NodeState) bool {
+		for _, s := range states {
+			if StatesEqual(state, s) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Restricts the query to the Nodes accepted by the NodeFilterFn.
+func (q *NodeQuery) Where(fn NodeFilterFn) *NodeQuery {
+	q.filters = append(q.filters, fn)
+	return q
+}
+
+// Returns the IDs of the selected Nodes.
+func (q *NodeQuery) IDs() NodeIDs {
+	var candidates NodeIDs
+	if q.ids != nil {
+		candidates = q.ids.ids
+	} else {
+		candidates = make(NodeIDs, len(q.env.Nodes))
+		for i, node := range q.env.Nodes {
+			candidates[i] = node.ID()
+		}
+	}
+
+	ids := NodeIDs{}
+Outer:
+	for _, id := range candidates {
+		state := q.env.StatesMap[id]
+		for _, fn := range q.filters {
+			if !fn(id, state) {
+				continue Outer
+			}
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Returns the selected Nodes.
+func (q *NodeQuery) Nodes() Nodes {
+	ids := q.IDs()
+	nodes := make(Nodes, len(ids))
+	for i, id := range ids {
+		nodes[i] = q.env.NodesMap[id]
+	}
+	return nodes
+}
+
+// Returns the states of the selected Nodes.
+func (q *NodeQuery) States() NodeStates {
+	ids := q.IDs()
+	states := make(NodeStates, len(ids))
+	for i, id := range ids {
+		states[i] = q.env.StatesMap[id]
+	}
+	return states
+}
+
+// Returns the number of selected Nodes.
+func (q *NodeQuery) Count() int {
+	return len(q.IDs())
+}
+
+// Returns whether any Node is selected.
+func (q *NodeQuery) Any() bool {
+	return q.Count() > 0
+}
+
+// Compares two states without panicking on uncomparable values, which are compared deeply instead.
+// This includes comparable types that hold uncomparable values, e.g. structs with an interface field holding a slice.
+func StatesEqual(a, b NodeState) (equal bool) {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if !ta.Comparable() {
+		return reflect.DeepEqual(a, b)
+	}
+	defer func() {
+		if recover() != nil {
+			equal = reflect.DeepEqual(a, b)
+		}
+	}()
+	return a == b
+}
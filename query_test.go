@@ -0,0 +1,61 @@
+package gwc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Query(t *testing.T) {
+	ne := newDefaultTestNodesEnvironment()
+	ne.StatesMap["2"] = "water"
+	ne.CollapsedMap["2"] = 0
+	ne.StatesMap["4"] = "grass"
+	ne.CollapsedMap["4"] = 1
+	ne.StatesMap["6"] = "water"
+	ne.CollapsedMap["6"] = 2
+
+	assert.EqualValues(t, NodeIDs{"0", "1", "2", "3", "4", "5", "6"}, ne.Query().IDs())
+	assert.EqualValues(t, NodeIDs{"2", "4", "6"}, ne.Query().Collapsed().IDs())
+	assert.EqualValues(t, NodeIDs{"0", "1", "3", "5"}, ne.Query().Uncollapsed().IDs())
+	assert.EqualValues(t, NodeIDs{"2", "6"}, ne.Query().InState("water").IDs())
+	assert.EqualValues(t, NodeIDs{"2", "4", "6"}, ne.Query().InState("water", "grass").IDs())
+
+	// Ranges keep their order by distance and can be combined.
+	assert.EqualValues(t, NodeIDs{"6", "4", "2", "5"}, ne.Query().Within("6", 2).IDs())
+	assert.EqualValues(t, NodeIDs{"2", "5"}, ne.Query().Within("6", 2).Within("3", 1).Except("3").IDs())
+	assert.EqualValues(t, NodeIDs{"2"}, ne.Query().Within("6", 2).Within("3", 1).Collapsed().IDs())
+	assert.EqualValues(t, NodeIDs{"2"}, ne.Query().Within("6", 2).Collapsed().InState("water").Where(func(id NodeID, _ NodeState) bool {
+		return id != "6"
+	}).IDs())
+	assert.EqualValues(t, NodeIDs{"0", "1", "3", "4"}, ne.Query().NeighboursOf("2").IDs())
+	assert.EqualValues(t, NodeIDs{"4"}, ne.Query().In("4", "x", "4").IDs())
+
+	assert.EqualValues(t, NodeStates{"water", "grass"}, ne.Query().Within("6", 1).States())
+	assert.EqualValues(t, NodeStates{"grass"}, ne.Query().NeighboursOf("6").States())
+	assert.EqualValues(t, Nodes{ne.NodesMap["5"]}, ne.Query().Within("5", 0).Nodes())
+	assert.Equal(t, 2, ne.Query().InState("water").Count())
+	assert.False(t, ne.Query().InState("lava").Any())
+
+	// Uncomparable states don't cause panics.
+	ne.StatesMap["0"] = []string{"a"}
+	ne.CollapsedMap["0"] = 3
+	assert.NotPanics(t, func() {
+		assert.EqualValues(t, NodeIDs{"0"}, ne.Query().InState([]string{"a"}).IDs())
+	})
+}
+
+func Test_StatesEqual(t *testing.T) {
+	assert.True(t, StatesEqual(nil, nil))
+	assert.True(t, StatesEqual("a", "a"))
+	assert.True(t, StatesEqual(map[string]int{"a": 1}, map[string]int{"a": 1}))
+	assert.False(t, StatesEqual("a", nil))
+	assert.False(t, StatesEqual(1, int64(1)))
+	assert.False(t, StatesEqual([]int{1}, []int{2}))
+
+	// Comparable types holding uncomparable values are compared deeply as well.
+	type wrap struct{ v interface{} }
+	assert.True(t, StatesEqual(wrap{[]int{1}}, wrap{[]int{1}}))
+	assert.False(t, StatesEqual(wrap{[]int{1}}, wrap{[]int{2}}))
+	assert.True(t, StatesEqual(wrap{1}, wrap{1}))
+}
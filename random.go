@@ -0,0 +1,96 @@
+package gwc
+
+import "math/bits"
+
+// Random is the source of randomness used for collapsing. It is implemented by *math/rand.Rand,
+// but math/rand's output may change between Go releases. Use NewXoshiro256() for output that stays the same.
+type Random interface {
+	// Returns a pseudo-random number in [0.0,1.0).
+	Float64() float64
+	// Returns a pseudo-random number in [0,n). It panics if n <= 0.
+	Intn(n int) int
+	// Returns a pseudo-random permutation of the integers [0,n).
+	Perm(n int) []int
+	// Returns a pseudo-random 64-bit value.
+	Uint64() uint64
+}
+
+// Builds a Xoshiro256 generator, whose state is derived from the seed using SplitMix64.
+func NewXoshiro256(seed uint64) *Xoshiro256 {
+	x := &Xoshiro256{}
+	for i := range x.s {
+		x.s[i] = mix64(seed)
+		seed += 0x9e3779b97f4a7c15
+	}
+	return x
+}
+
+// Xoshiro256 is an implementation of the xoshiro256** generator, which yields identical output on every platform and Go release.
+// All methods are implemented by this package, so that their output only depends on the seed.
+type Xoshiro256 struct {
+	s [4]uint64
+}
+
+func (x *Xoshiro256) Uint64() uint64 {
+	result := bits.RotateLeft64(x.s[1]*5, 7) * 9
+	t := x.s[1] << 17
+
+	x.s[2] ^= x.s[0]
+	x.s[3] ^= x.s[1]
+	x.s[1] ^= x.s[2]
+	x.s[0] ^= x.s[3]
+	x.s[2] ^= t
+	x.s[3] = bits.RotateLeft64(x.s[3], 45)
+
+	return result
+}
+
+// Uses the upper 53 bits, so that every returned float is equally likely.
+func (x *Xoshiro256) Float64() float64 {
+	return float64(x.Uint64()>>11) / (1 << 53)
+}
+
+// Uses Lemire's multiply-and-reject method, so that the result is unbiased.
+func (x *Xoshiro256) Intn(n int) int {
+	if n <= 0 {
+		panic("invalid argument to Intn")
+	}
+	bound := uint64(n)
+	hi, lo := bits.Mul64(x.Uint64(), bound)
+	if lo < bound {
+		threshold := -bound % bound
+		for lo < threshold {
+			hi, lo = bits.Mul64(x.Uint64(), bound)
+		}
+	}
+	return int(hi)
+}
+
+// Uses an inside-out Fisher-Yates shuffle.
+func (x *Xoshiro256) Perm(n int) []int {
+	perm := make([]int, n)
+	for i := 0; i < n; i++ {
+		j := x.Intn(i + 1)
+		perm[i] = perm[j]
+		perm[j] = i
+	}
+	return perm
+}
+
+// Derives the seed of a Node's random stream from a global seed, the Node's ID and the attempt of collapsing it.
+func NodeSeed(seed uint64, id NodeID, attempt int) uint64 {
+	// Hash the ID using FNV-1a and mix all parts using the SplitMix64 finalizer.
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(id); i++ {
+		h ^= uint64(id[i])
+		h *= 1099511628211
+	}
+	return mix64(mix64(seed^h) + uint64(attempt))
+}
+
+func mix64(z uint64) uint64 {
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
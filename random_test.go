@@ -0,0 +1,77 @@
+package gwc
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Xoshiro256(t *testing.T) {
+	// The output must never change, as it is used to reproduce saved seeds.
+	x := NewXoshiro256(42)
+	assert.Equal(t, uint64(1546998764402558742), x.Uint64())
+	assert.Equal(t, uint64(6990951692964543102), x.Uint64())
+	assert.Equal(t, uint64(12544586762248559009), x.Uint64())
+
+	x = NewXoshiro256(1337)
+	for i := 0; i < 1000; i++ {
+		f := x.Float64()
+		assert.True(t, f >= 0 && f < 1)
+		n := x.Intn(7)
+		assert.True(t, n >= 0 && n < 7)
+	}
+	assert.Panics(t, func() {
+		x.Intn(0)
+	})
+
+	perm := x.Perm(10)
+	sort.Ints(perm)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, perm)
+	assert.Empty(t, x.Perm(0))
+}
+
+func Test_Random(t *testing.T) {
+	// Both, the built-in generator and math/rand, can be used for collapsing.
+	for _, rnd := range []Random{NewXoshiro256(42), rand.New(rand.NewSource(42))} {
+		super := newAbcdNodeSuperposition()
+		collapsed := New(rnd, RandomCollapseOrder, newDefaultTestNodes(super...)).Collapse()
+		assert.Len(t, collapsed.Collapsed(), 7)
+	}
+
+	// The built-in generator yields the same map for a seed on every platform and Go release.
+	super := newAbcdNodeSuperposition()
+	collapsed := New(NewXoshiro256(42), RandomStreakCollapseOrder, newDefaultTestNodes(super...)).Collapse()
+	assert.EqualValues(t, NodeIDs{"2", "0", "4", "5", "3", "6", "1"}, collapsed.Collapsed())
+	assert.EqualValues(t, NodeStates{"B", "A", "A", "A", "B", "B", "A"}, collapsed.States())
+}
+
+func Test_WithNodeStreams(t *testing.T) {
+	abcd := newAbcdNodeSuperposition()
+	nodes := newDefaultTestNodes(abcd...)
+	collapsed := New(NewXoshiro256(1), AscendingCollapseOrder, nodes, WithNodeStreams(42)).Collapse()
+	states := collapsed.States()
+
+	// The same seed yields the same states, regardless of the Random used for the order.
+	again := New(rand.New(rand.NewSource(7)), AscendingCollapseOrder, nodes, WithNodeStreams(42)).Collapse()
+	assert.EqualValues(t, states, again.States())
+
+	// Pinning a single Node to another state doesn't change any other Node.
+	edited := newDefaultTestNodes(abcd...)
+	edited[3] = NewSuperpositionNode("3", NodeSuperposition{
+		func(_ Random, _ NodeEnvironment) (NodeProbability, NodeState) {
+			return 1, "E"
+		},
+	}, edited[3].Neighbours()...)
+	changed := New(NewXoshiro256(1), DescendingCollapseOrder, edited, WithNodeStreams(42)).Collapse()
+	expected := append(NodeStates{}, states...)
+	expected[3] = "E"
+	assert.EqualValues(t, expected, changed.States())
+
+	// Another seed, or collapsing a Node again, yields other random numbers.
+	assert.NotEqual(t, NodeSeed(42, "3", 0), NodeSeed(43, "3", 0))
+	assert.NotEqual(t, NodeSeed(42, "3", 0), NodeSeed(42, "3", 1))
+	assert.NotEqual(t, NodeSeed(42, "3", 0), NodeSeed(42, "4", 0))
+	assert.Equal(t, 1, changed.attempts["3"])
+}
@@ -0,0 +1,99 @@
+package gwc
+
+// Builds a NodeIDSet containing the provided IDs.
+func NewNodeIDSet(ids ...NodeID) *NodeIDSet {
+	s := &NodeIDSet{index: make(map[NodeID]int, len(ids))}
+	s.Add(ids...)
+	return s
+}
+
+// NodeIDSet is a hash backed set of NodeIDs, which iterates in order of insertion.
+// The zero value is not usable, sets need to be built using NewNodeIDSet().
+type NodeIDSet struct {
+	ids   NodeIDs
+	index map[NodeID]int
+}
+
+// Adds the IDs to the set, ignoring those it already contains.
+func (s *NodeIDSet) Add(ids ...NodeID) *NodeIDSet {
+	for _, id := range ids {
+		if _, exists := s.index[id]; !exists {
+			s.index[id] = len(s.ids)
+			s.ids = append(s.ids, id)
+		}
+	}
+	return s
+}
+
+// Removes the IDs from the set. Removing is linear in the size of the set, as the order has to be kept.
+func (s *NodeIDSet) Remove(ids ...NodeID) *NodeIDSet {
+	removed := false
+	for _, id := range ids {
+		if _, exists := s.index[id]; exists {
+			delete(s.index, id)
+			removed = true
+		}
+	}
+	if removed {
+		kept := make(NodeIDs, 0, len(s.index))
+		for _, id := range s.ids {
+			if _, exists := s.index[id]; exists {
+				s.index[id] = len(kept)
+				kept = append(kept, id)
+			}
+		}
+		s.ids = kept
+	}
+	return s
+}
+
+func (s *NodeIDSet) Contains(id NodeID) bool {
+	_, exists := s.index[id]
+	return exists
+}
+
+func (s *NodeIDSet) Len() int {
+	return len(s.ids)
+}
+
+// Returns the IDs of the set in order of insertion.
+func (s *NodeIDSet) IDs() NodeIDs {
+	return append(NodeIDs{}, s.ids...)
+}
+
+// Returns a new set containing the IDs of both sets, ordered by this set first.
+func (s *NodeIDSet) Union(other *NodeIDSet) *NodeIDSet {
+	return NewNodeIDSet(s.ids...).Add(other.ids...)
+}
+
+// Returns a new set containing the IDs that are in both sets, ordered like this set.
+func (s *NodeIDSet) Intersect(other *NodeIDSet) *NodeIDSet {
+	xs := NewNodeIDSet()
+	for _, id := range s.ids {
+		if other.Contains(id) {
+			xs.Add(id)
+		}
+	}
+	return xs
+}
+
+// Returns a new set containing the IDs of this set that aren't in the other set.
+func (s *NodeIDSet) Difference(other *NodeIDSet) *NodeIDSet {
+	xs := NewNodeIDSet()
+	for _, id := range s.ids {
+		if !other.Contains(id) {
+			xs.Add(id)
+		}
+	}
+	return xs
+}
+
+// Returns a new set containing the IDs that are in exactly one of both sets, ordered by this set first.
+func (s *NodeIDSet) SymmetricDifference(other *NodeIDSet) *NodeIDSet {
+	return s.Difference(other).Add(other.Difference(s).ids...)
+}
+
+// Builds a NodeIDSet containing the IDs of the list.
+func (ids NodeIDs) Set() *NodeIDSet {
+	return NewNodeIDSet(ids...)
+}
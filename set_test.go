@@ -0,0 +1,36 @@
+package gwc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NodeIDSet(t *testing.T) {
+	as := NewNodeIDSet("0", "1", "2", "3", "1")
+	bs := NodeIDs{"5", "4", "3", "2"}.Set()
+
+	assert.Equal(t, 4, as.Len())
+	assert.True(t, as.Contains("1"))
+	assert.False(t, as.Contains("4"))
+	assert.EqualValues(t, NodeIDs{"0", "1", "2", "3"}, as.IDs())
+
+	assert.EqualValues(t, NodeIDs{"0", "1", "2", "3", "5", "4"}, as.Union(bs).IDs())
+	assert.EqualValues(t, NodeIDs{"2", "3"}, as.Intersect(bs).IDs())
+	assert.EqualValues(t, NodeIDs{"3", "2"}, bs.Intersect(as).IDs())
+	assert.EqualValues(t, NodeIDs{"0", "1"}, as.Difference(bs).IDs())
+	assert.EqualValues(t, NodeIDs{"0", "1", "5", "4"}, as.SymmetricDifference(bs).IDs())
+
+	// Operations don't modify their operands.
+	assert.Equal(t, 4, as.Len())
+	assert.Equal(t, 4, bs.Len())
+
+	as.Remove("1", "7").Add("1", "6")
+	assert.EqualValues(t, NodeIDs{"0", "2", "3", "1", "6"}, as.IDs())
+	assert.True(t, as.Contains("6"))
+	assert.False(t, as.Remove("6").Contains("6"))
+
+	ids := as.IDs()
+	ids[0] = "x"
+	assert.False(t, as.Contains("x"))
+}
@@ -0,0 +1,464 @@
+package gwc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Directions used by the simple tiled model, in the same order as the reference implementation.
+const (
+	TiledLeft = iota
+	TiledDown
+	TiledRight
+	TiledUp
+)
+
+var (
+	tiledDX = [4]int{-1, 0, 1, 0}
+	tiledDY = [4]int{0, 1, 0, -1}
+)
+
+// Builds the NodeID of the tile at the given grid coordinates.
+func TiledNodeID(x, y int) NodeID {
+	return strconv.Itoa(x) + ":" + strconv.Itoa(y)
+}
+
+// TiledTileset holds the tiles and adjacency rules of a simple tiled model tileset.
+type TiledTileset struct {
+	Size     int
+	Unique   bool
+	Tiles    []TiledTile
+	Variants []TiledVariant
+
+	index      map[NodeState]int
+	propagator [4][][]bool
+}
+
+// TiledTile is a single tile definition of the tileset, before expanding its symmetries.
+type TiledTile struct {
+	Name        string
+	Symmetry    string
+	Weight      float64
+	Cardinality int
+	// Index of the tile's first variant within TiledTileset.Variants.
+	First int
+}
+
+// TiledVariant is a rotated and/or reflected instance of a tile and is used as NodeState.
+type TiledVariant struct {
+	// Name is the state of the variant, formatted as "<tile> <variant>" like in the reference format.
+	Name    string
+	Tile    int
+	Variant int
+	Weight  float64
+	// Action maps the 8 symmetries of the square (4 rotations followed by 4 reflections) to variant indexes.
+	Action [8]int
+	Image  image.Image
+}
+
+type tiledXMLSet struct {
+	Size   int  `xml:"size,attr"`
+	Unique bool `xml:"unique,attr"`
+	Tiles  []struct {
+		Name     string   `xml:"name,attr"`
+		Symmetry string   `xml:"symmetry,attr"`
+		Weight   *float64 `xml:"weight,attr"`
+	} `xml:"tiles>tile"`
+	Neighbours []struct {
+		Left  string `xml:"left,attr"`
+		Right string `xml:"right,attr"`
+	} `xml:"neighbors>neighbor"`
+	Subsets []struct {
+		Name  string `xml:"name,attr"`
+		Tiles []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"tile"`
+	} `xml:"subsets>subset"`
+}
+
+// Loads a simple tiled model tileset from the XML file at path. Tile images are read from the same directory.
+func LoadTiledTilesetFile(path string, subset string) (*TiledTileset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ts, err := LoadTiledTileset(f, subset)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.LoadImages(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Parses a simple tiled model tileset from XML. If subset is not empty, only the tiles of that subset are used.
+func LoadTiledTileset(r io.Reader, subset string) (*TiledTileset, error) {
+	var set tiledXMLSet
+	if err := xml.NewDecoder(r).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	var allowed map[string]bool
+	if subset != "" {
+		allowed = map[string]bool{}
+		for _, s := range set.Subsets {
+			if s.Name == subset {
+				for _, t := range s.Tiles {
+					allowed[t.Name] = true
+				}
+			}
+		}
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("tileset has no subset %q", subset)
+		}
+	}
+
+	ts := &TiledTileset{
+		Size:   set.Size,
+		Unique: set.Unique,
+		index:  map[NodeState]int{},
+	}
+	first := map[string]int{}
+
+	for _, xt := range set.Tiles {
+		if allowed != nil && !allowed[xt.Name] {
+			continue
+		}
+		if _, exists := first[xt.Name]; exists {
+			return nil, fmt.Errorf("tile %q is defined twice", xt.Name)
+		}
+
+		cardinality, a, b, err := tiledSymmetry(xt.Symmetry)
+		if err != nil {
+			return nil, fmt.Errorf("tile %q: %v", xt.Name, err)
+		}
+		weight := 1.0
+		if xt.Weight != nil {
+			weight = *xt.Weight
+		}
+
+		offset := len(ts.Variants)
+		first[xt.Name] = offset
+		ts.Tiles = append(ts.Tiles, TiledTile{
+			Name:        xt.Name,
+			Symmetry:    xt.Symmetry,
+			Weight:      weight,
+			Cardinality: cardinality,
+			First:       offset,
+		})
+
+		for t := 0; t < cardinality; t++ {
+			v := TiledVariant{
+				Name:    xt.Name + " " + strconv.Itoa(t),
+				Tile:    len(ts.Tiles) - 1,
+				Variant: t,
+				Weight:  weight,
+				Action: [8]int{
+					t, a(t), a(a(t)), a(a(a(t))),
+					b(t), b(a(t)), b(a(a(t))), b(a(a(a(t)))),
+				},
+			}
+			for i := range v.Action {
+				v.Action[i] += offset
+			}
+			ts.index[v.Name] = len(ts.Variants)
+			ts.Variants = append(ts.Variants, v)
+		}
+	}
+
+	num := len(ts.Variants)
+	for d := range ts.propagator {
+		ts.propagator[d] = make([][]bool, num)
+		for t := range ts.propagator[d] {
+			ts.propagator[d][t] = make([]bool, num)
+		}
+	}
+
+	// Expand every neighbour pair to all of its symmetric counterparts, exactly like the reference implementation.
+	resolve := func(s string) (int, bool, error) {
+		parts := strings.Fields(s)
+		if len(parts) == 0 {
+			return 0, false, fmt.Errorf("empty neighbour")
+		}
+		f, exists := first[parts[0]]
+		if !exists {
+			if allowed != nil && !allowed[parts[0]] {
+				return 0, false, nil
+			}
+			return 0, false, fmt.Errorf("unknown tile %q", parts[0])
+		}
+		variant := 0
+		if len(parts) > 1 {
+			v, err := strconv.Atoi(parts[1])
+			if err != nil || v < 0 || v > 7 {
+				return 0, false, fmt.Errorf("invalid variant in %q", s)
+			}
+			variant = v
+		}
+		return ts.Variants[f].Action[variant], true, nil
+	}
+	for _, xn := range set.Neighbours {
+		l, lok, err := resolve(xn.Left)
+		if err != nil {
+			return nil, err
+		}
+		r, rok, err := resolve(xn.Right)
+		if err != nil {
+			return nil, err
+		}
+		if !lok || !rok {
+			continue
+		}
+		d := ts.Variants[l].Action[1]
+		u := ts.Variants[r].Action[1]
+		act := func(t, i int) int { return ts.Variants[t].Action[i] }
+
+		ts.propagator[TiledLeft][r][l] = true
+		ts.propagator[TiledLeft][act(r, 6)][act(l, 6)] = true
+		ts.propagator[TiledLeft][act(l, 4)][act(r, 4)] = true
+		ts.propagator[TiledLeft][act(l, 2)][act(r, 2)] = true
+
+		ts.propagator[TiledDown][u][d] = true
+		ts.propagator[TiledDown][act(d, 6)][act(u, 6)] = true
+		ts.propagator[TiledDown][act(u, 4)][act(d, 4)] = true
+		ts.propagator[TiledDown][act(d, 2)][act(u, 2)] = true
+	}
+	for t1 := 0; t1 < num; t1++ {
+		for t2 := 0; t2 < num; t2++ {
+			ts.propagator[TiledRight][t2][t1] = ts.propagator[TiledLeft][t1][t2]
+			ts.propagator[TiledUp][t2][t1] = ts.propagator[TiledDown][t1][t2]
+		}
+	}
+
+	return ts, nil
+}
+
+// Returns the cardinality and the rotation and reflection actions of the given symmetry class.
+func tiledSymmetry(symmetry string) (int, func(int) int, func(int) int, error) {
+	switch symmetry {
+	case "L":
+		return 4, func(i int) int { return (i + 1) % 4 }, func(i int) int {
+			if i%2 == 0 {
+				return i + 1
+			}
+			return i - 1
+		}, nil
+	case "T":
+		return 4, func(i int) int { return (i + 1) % 4 }, func(i int) int {
+			if i%2 == 0 {
+				return i
+			}
+			return 4 - i
+		}, nil
+	case "I":
+		return 2, func(i int) int { return 1 - i }, func(i int) int { return i }, nil
+	case "\\":
+		return 2, func(i int) int { return 1 - i }, func(i int) int { return 1 - i }, nil
+	case "F":
+		return 8, func(i int) int {
+				if i < 4 {
+					return (i + 1) % 4
+				}
+				return 4 + (i-1)%4
+			}, func(i int) int {
+				if i < 4 {
+					return i + 4
+				}
+				return i - 4
+			}, nil
+	case "X", "":
+		return 1, func(i int) int { return i }, func(i int) int { return i }, nil
+	}
+	return 0, nil, nil, fmt.Errorf("unknown symmetry %q", symmetry)
+}
+
+// Returns whether state b may be placed in direction dir of state a.
+func (ts *TiledTileset) Allows(dir int, a, b NodeState) bool {
+	ia, aok := ts.index[a]
+	ib, bok := ts.index[b]
+	if !aok || !bok || dir < 0 || dir > 3 {
+		return false
+	}
+	return ts.propagator[dir][ia][ib]
+}
+
+// Builds the NodeSuperposition of the tile at the given coordinates.
+// Every variant is weighted by its tile weight, unless an already collapsed neighbour forbids it.
+// The weights are scaled so that every allowed variant has a weight of at least 1, as SuperpositionStateFn picks
+// a random state among all variants, forbidden ones included, if the weights sum up to less than 1.
+func (ts *TiledTileset) Superposition(neighbours [4]NodeID) NodeSuperposition {
+	scale := 1.0
+	for _, variant := range ts.Variants {
+		if variant.Weight > 0 && variant.Weight < 1 && 1/variant.Weight > scale {
+			scale = 1 / variant.Weight
+		}
+	}
+
+	super := make(NodeSuperposition, len(ts.Variants))
+	for i := range ts.Variants {
+		idx, variant := i, ts.Variants[i]
+		super[i] = func(_ Random, env NodeEnvironment) (NodeProbability, NodeState) {
+			for d, ni := range neighbours {
+				if ni == "" {
+					continue
+				}
+				if _, collapsed := env.CollapsedMap[ni]; !collapsed {
+					continue
+				}
+				other, known := ts.index[env.StatesMap[ni]]
+				if !known || !ts.propagator[d][idx][other] {
+					return 0, variant.Name
+				}
+			}
+			return variant.Weight * scale, variant.Name
+		}
+	}
+	return super
+}
+
+// Builds a width*height grid of Nodes, whose states are the tileset's variants.
+// If periodic is true, the grid wraps around at its borders.
+func (ts *TiledTileset) Nodes(width, height int, periodic bool) Nodes {
+	nodes := make(Nodes, 0, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dirs [4]NodeID
+			ids := NodeIDs{}
+			for d := range dirs {
+				nx, ny := x+tiledDX[d], y+tiledDY[d]
+				if periodic {
+					nx, ny = (nx+width)%width, (ny+height)%height
+				} else if nx < 0 || ny < 0 || nx >= width || ny >= height {
+					continue
+				}
+				if nx == x && ny == y {
+					continue
+				}
+				dirs[d] = TiledNodeID(nx, ny)
+				ids = append(ids, dirs[d])
+			}
+			nodes = append(nodes, NewSuperpositionNode(TiledNodeID(x, y), ts.Superposition(dirs), ids...))
+		}
+	}
+	return nodes
+}
+
+// Loads the tile images from dir, using the file naming scheme of the reference implementation.
+func (ts *TiledTileset) LoadImages(dir string) error {
+	for _, tile := range ts.Tiles {
+		names := []string{tile.Name + ".png"}
+		if ts.Unique {
+			names = make([]string, tile.Cardinality)
+			for t := range names {
+				names[t] = tile.Name + " " + strconv.Itoa(t) + ".png"
+			}
+		}
+
+		imgs := make([]image.Image, len(names))
+		for i, name := range names {
+			img, err := loadImage(filepath.Join(dir, name))
+			if err != nil {
+				return err
+			}
+			imgs[i] = img
+		}
+		if err := ts.SetTileImages(tile.Name, imgs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// Sets the images of a tile's variants. If only a single image is provided, the remaining variants are derived by rotating and reflecting it.
+func (ts *TiledTileset) SetTileImages(name string, imgs ...image.Image) error {
+	for _, tile := range ts.Tiles {
+		if tile.Name != name {
+			continue
+		}
+		if len(imgs) != 1 && len(imgs) != tile.Cardinality {
+			return fmt.Errorf("tile %q needs 1 or %d images, got %d", name, tile.Cardinality, len(imgs))
+		}
+		for t := 0; t < tile.Cardinality; t++ {
+			var img image.Image
+			switch {
+			case len(imgs) > 1:
+				img = imgs[t]
+			case t == 0:
+				img = imgs[0]
+			case t <= 3:
+				img = rotateImage(ts.Variants[tile.First+t-1].Image)
+			default:
+				img = reflectImage(ts.Variants[tile.First+t-4].Image)
+			}
+			ts.Variants[tile.First+t].Image = img
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown tile %q", name)
+}
+
+// Rotates the image by 90 degrees counter-clockwise, which matches the rotation the variants' actions assume.
+func rotateImage(src image.Image) image.Image {
+	b := src.Bounds()
+	w, h := b.Dy(), b.Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(x, y, src.At(b.Min.X+h-1-y, b.Min.Y+x))
+		}
+	}
+	return dst
+}
+
+// Reflects the image horizontally.
+func reflectImage(src image.Image) image.Image {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.Set(b.Dx()-1-x, y, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// Renders the collapsed width*height grid by putting together the images of the Nodes' states.
+// Uncollapsed Nodes and states without image are left transparent.
+func (ts *TiledTileset) Render(env NodeEnvironment, width, height int) *image.RGBA {
+	size := ts.Size
+	dst := image.NewRGBA(image.Rect(0, 0, width*size, height*size))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			idx, known := ts.index[env.StatesMap[TiledNodeID(x, y)]]
+			if !known || ts.Variants[idx].Image == nil {
+				continue
+			}
+			img := ts.Variants[idx].Image
+			rect := image.Rect(x*size, y*size, (x+1)*size, (y+1)*size)
+			draw.Draw(dst, rect, img, img.Bounds().Min, draw.Src)
+		}
+	}
+	return dst
+}
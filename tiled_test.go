@@ -0,0 +1,140 @@
+package gwc
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTiledXML = `<set size="2">
+	<tiles>
+		<tile name="grass" symmetry="X" weight="2.0"/>
+		<tile name="water" symmetry="X"/>
+		<tile name="road" symmetry="I"/>
+		<tile name="corner" symmetry="L"/>
+	</tiles>
+	<neighbors>
+		<neighbor left="grass" right="grass"/>
+		<neighbor left="water" right="water"/>
+		<neighbor left="road 1" right="road 1"/>
+	</neighbors>
+	<subsets>
+		<subset name="land">
+			<tile name="grass"/>
+			<tile name="road"/>
+		</subset>
+	</subsets>
+</set>`
+
+func Test_LoadTiledTileset(t *testing.T) {
+	ts, err := LoadTiledTileset(strings.NewReader(testTiledXML), "")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, ts.Size)
+	assert.Len(t, ts.Tiles, 4)
+	assert.Len(t, ts.Variants, 1+1+2+4)
+	assert.Equal(t, 2.0, ts.Variants[0].Weight)
+	assert.Equal(t, "road 1", ts.Variants[3].Name)
+	assert.Equal(t, [8]int{4, 5, 6, 7, 5, 4, 7, 6}, ts.Variants[4].Action)
+
+	assert.True(t, ts.Allows(TiledLeft, "grass 0", "grass 0"))
+	assert.True(t, ts.Allows(TiledUp, "grass 0", "grass 0"))
+	assert.False(t, ts.Allows(TiledRight, "grass 0", "water 0"))
+	assert.True(t, ts.Allows(TiledRight, "road 1", "road 1"))
+	assert.True(t, ts.Allows(TiledDown, "road 0", "road 0"))
+	assert.False(t, ts.Allows(TiledDown, "road 1", "road 1"))
+	assert.False(t, ts.Allows(TiledDown, "unknown", "road 1"))
+
+	land, err := LoadTiledTileset(strings.NewReader(testTiledXML), "land")
+	assert.NoError(t, err)
+	assert.Len(t, land.Variants, 3)
+
+	_, err = LoadTiledTileset(strings.NewReader(testTiledXML), "sea")
+	assert.Error(t, err)
+	_, err = LoadTiledTileset(strings.NewReader(`<set><tiles><tile name="a" symmetry="Q"/></tiles></set>`), "")
+	assert.Error(t, err)
+	_, err = LoadTiledTileset(strings.NewReader(`<set><neighbors><neighbor left="a" right="b"/></neighbors></set>`), "")
+	assert.Error(t, err)
+}
+
+func Test_TiledCollapseAndRender(t *testing.T) {
+	ts, err := LoadTiledTileset(strings.NewReader(`<set size="2">
+		<tiles>
+			<tile name="grass" symmetry="X"/>
+			<tile name="water" symmetry="X"/>
+		</tiles>
+		<neighbors>
+			<neighbor left="grass" right="grass"/>
+			<neighbor left="water" right="water"/>
+		</neighbors>
+	</set>`), "")
+	assert.NoError(t, err)
+
+	nodes := ts.Nodes(3, 2, false)
+	assert.Len(t, nodes, 6)
+	assert.EqualValues(t, NodeIDs{"0:1", "2:1", "1:0"}, nodes[4].Neighbours())
+
+	rnd := rand.New(rand.NewSource(42))
+	env := New(rnd, AscendingCollapseOrder, nodes).Collapse()
+	states := env.States()
+	for _, state := range states {
+		assert.Equal(t, states[0], state)
+	}
+
+	green := image.NewUniform(color.RGBA{0, 255, 0, 255})
+	blue := image.NewUniform(color.RGBA{0, 0, 255, 255})
+	assert.NoError(t, ts.SetTileImages("grass", green))
+	assert.NoError(t, ts.SetTileImages("water", blue))
+	assert.Error(t, ts.SetTileImages("lava", blue))
+
+	img := ts.Render(env, 3, 2)
+	assert.Equal(t, image.Rect(0, 0, 6, 4), img.Bounds())
+	expected := color.RGBA{0, 255, 0, 255}
+	if states[0] == "water 0" {
+		expected = color.RGBA{0, 0, 255, 255}
+	}
+	assert.Equal(t, expected, img.RGBAAt(5, 3))
+}
+
+func Test_RotateReflectImage(t *testing.T) {
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(2, 0, red)
+	src.Set(0, 1, blue)
+
+	// Rotating counter-clockwise moves the top right corner to the top left and the bottom left corner to the bottom right.
+	rotated := rotateImage(src)
+	assert.Equal(t, image.Rect(0, 0, 2, 3), rotated.Bounds())
+	assert.Equal(t, red, rotated.At(0, 0))
+	assert.Equal(t, blue, rotated.At(1, 2))
+
+	reflected := reflectImage(src)
+	assert.Equal(t, red, reflected.At(0, 0))
+	assert.Equal(t, blue, reflected.At(2, 1))
+}
+
+func Test_TiledSuperpositionLowWeights(t *testing.T) {
+	ts, err := LoadTiledTileset(strings.NewReader(`<set size="1">
+		<tiles>
+			<tile name="grass" symmetry="X" weight="0.1"/>
+			<tile name="water" symmetry="X" weight="0.1"/>
+		</tiles>
+		<neighbors>
+			<neighbor left="grass" right="grass"/>
+			<neighbor left="water" right="water"/>
+		</neighbors>
+	</set>`), "")
+	assert.NoError(t, err)
+
+	for seed := int64(0); seed < 20; seed++ {
+		env := New(rand.New(rand.NewSource(seed)), AscendingCollapseOrder, ts.Nodes(4, 4, false)).Collapse()
+		states := env.States()
+		for _, state := range states {
+			assert.Equal(t, states[0], state)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package gwc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationErrorKind classifies the problems found by Validate.
+type ValidationErrorKind int
+
+const (
+	// A Node is nil.
+	NilNode ValidationErrorKind = iota
+	// A Node has an empty ID, which clashes with the "" returned by CollapseOrderFns at the end of collapsing.
+	EmptyID
+	// Several Nodes share the same ID and would overwrite each other within the NodesMap.
+	DuplicateID
+	// A Node lists a neighbour that doesn't exist.
+	UnknownNeighbour
+	// A Node lists itself as neighbour.
+	SelfLoop
+	// A Node lists a neighbour that doesn't list the Node in return.
+	AsymmetricEdge
+	// A collapse order contains an ID that doesn't belong to any Node.
+	UnknownOrderedID
+	// A collapse order contains an ID more than once.
+	DuplicateOrderedID
+	// A Node is missing from a collapse order.
+	UnorderedNode
+	// A Node is part of a cycle, although an acyclic graph is required.
+	Cycle
+)
+
+func (k ValidationErrorKind) String() string {
+	switch k {
+	case NilNode:
+		return "nil node"
+	case EmptyID:
+		return "empty id"
+	case DuplicateID:
+		return "duplicate id"
+	case UnknownNeighbour:
+		return "unknown neighbour"
+	case SelfLoop:
+		return "self-loop"
+	case AsymmetricEdge:
+		return "asymmetric edge"
+	case UnknownOrderedID:
+		return "unknown ordered id"
+	case DuplicateOrderedID:
+		return "duplicate ordered id"
+	case UnorderedNode:
+		return "unordered node"
+	case Cycle:
+		return "cycle"
+	}
+	return fmt.Sprintf("ValidationErrorKind(%d)", int(k))
+}
+
+// ValidationError describes a single problem of a graph.
+type ValidationError struct {
+	Kind ValidationErrorKind
+	// Index of the offending Node within the validated Nodes, or of the offending ID within a validated order.
+	Index int
+	ID    NodeID
+	// Neighbour is set for errors concerning a single edge.
+	Neighbour NodeID
+}
+
+func (e ValidationError) Error() string {
+	if e.Neighbour != "" {
+		return fmt.Sprintf("%s: node %q (#%d) -> %q", e.Kind, e.ID, e.Index, e.Neighbour)
+	}
+	return fmt.Sprintf("%s: node %q (#%d)", e.Kind, e.ID, e.Index)
+}
+
+// ValidationErrors is the error returned by Validate, containing all problems in order of the validated Nodes.
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Returns the errors that are not of the provided kinds.
+func (errs ValidationErrors) Without(kinds ...ValidationErrorKind) ValidationErrors {
+	filtered := ValidationErrors{}
+Outer:
+	for _, e := range errs {
+		for _, k := range kinds {
+			if e.Kind == k {
+				continue Outer
+			}
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered
+}
+
+// Checks the Nodes for problems that would make collapsing them fail or behave unexpectedly.
+// Returns nil if the Nodes are valid, otherwise ValidationErrors.
+func Validate(nodes Nodes) error {
+	errs := ValidationErrors{}
+
+	nodes_map := NodesMap{}
+	for idx, node := range nodes {
+		if node == nil {
+			errs = append(errs, ValidationError{Kind: NilNode, Index: idx})
+			continue
+		}
+		id := node.ID()
+		if id == "" {
+			errs = append(errs, ValidationError{Kind: EmptyID, Index: idx})
+		}
+		if _, exists := nodes_map[id]; exists {
+			errs = append(errs, ValidationError{Kind: DuplicateID, Index: idx, ID: id})
+			continue
+		}
+		nodes_map[id] = node
+	}
+
+	for idx, node := range nodes {
+		if node == nil {
+			continue
+		}
+		id := node.ID()
+		for _, ni := range node.Neighbours() {
+			other, exists := nodes_map[ni]
+			switch {
+			case !exists:
+				errs = append(errs, ValidationError{UnknownNeighbour, idx, id, ni})
+			case ni == id:
+				errs = append(errs, ValidationError{SelfLoop, idx, id, ni})
+			case !other.Neighbours().Contains(id):
+				errs = append(errs, ValidationError{AsymmetricEdge, idx, id, ni})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Checks that the order contains the ID of every Node exactly once.
+// Returns nil if the order is valid, otherwise ValidationErrors.
+func ValidateCollapseOrder(order NodeIDs, nodes Nodes) error {
+	errs := ValidationErrors{}
+
+	known := NewNodeIDSet()
+	for _, node := range nodes {
+		if node != nil {
+			known.Add(node.ID())
+		}
+	}
+
+	ordered := NewNodeIDSet()
+	for idx, id := range order {
+		switch {
+		case !known.Contains(id):
+			errs = append(errs, ValidationError{Kind: UnknownOrderedID, Index: idx, ID: id})
+		case ordered.Contains(id):
+			errs = append(errs, ValidationError{Kind: DuplicateOrderedID, Index: idx, ID: id})
+		}
+		ordered.Add(id)
+	}
+	for idx, node := range nodes {
+		if node != nil && !ordered.Contains(node.ID()) {
+			errs = append(errs, ValidationError{Kind: UnorderedNode, Index: idx, ID: node.ID()})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
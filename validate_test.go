@@ -0,0 +1,67 @@
+package gwc
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate(t *testing.T) {
+	assert.NoError(t, Validate(newDefaultTestNodes()))
+	assert.NoError(t, Validate(Nodes{}))
+
+	nodes := Nodes{
+		NewNode("a", nil, "b", "x"),
+		NewNode("b", nil, "a", "b"),
+		NewNode("c", nil, "a"),
+		NewNode("a", nil),
+		NewNode("", nil),
+		nil,
+	}
+	err := Validate(nodes)
+	assert.Error(t, err)
+
+	errs, ok := err.(ValidationErrors)
+	assert.True(t, ok)
+	assert.EqualValues(t, ValidationErrors{
+		{Kind: DuplicateID, Index: 3, ID: "a"},
+		{Kind: EmptyID, Index: 4},
+		{Kind: NilNode, Index: 5},
+		{Kind: UnknownNeighbour, Index: 0, ID: "a", Neighbour: "x"},
+		{Kind: SelfLoop, Index: 1, ID: "b", Neighbour: "b"},
+		{Kind: AsymmetricEdge, Index: 2, ID: "c", Neighbour: "a"},
+	}, errs)
+	assert.Len(t, errs.Without(AsymmetricEdge, SelfLoop), 4)
+	assert.Equal(t, `unknown neighbour: node "a" (#0) -> "x"`, errs[3].Error())
+	assert.Equal(t, `duplicate id: node "a" (#3); empty id: node "" (#4)`, errs[:2].Error())
+}
+
+func Test_WithValidation(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+
+	sim := New(rnd, AscendingCollapseOrder, newDefaultTestNodes(), WithValidation())
+	assert.NoError(t, sim.Err())
+	env := sim.Collapse()
+	assert.Len(t, env.CollapsedMap, 7)
+
+	invalid := Nodes{NewNode("a", nil, "b"), NewNode("b", nil)}
+	sim = New(rnd, AscendingCollapseOrder, invalid, WithValidation())
+	assert.Error(t, sim.Err())
+	env = sim.Collapse()
+	assert.Empty(t, env.CollapsedMap)
+
+	sim = New(rnd, AscendingCollapseOrder, invalid, WithValidation(AsymmetricEdge))
+	assert.NoError(t, sim.Err())
+	env = sim.Collapse()
+	assert.Len(t, env.CollapsedMap, 2)
+
+	// Nil Nodes are reported instead of panicking.
+	sim = New(rnd, AscendingCollapseOrder, Nodes{NewNode("a", nil), nil}, WithValidation())
+	assert.Error(t, sim.Err())
+	assert.NotPanics(t, func() {
+		env = sim.Collapse()
+	})
+	assert.Empty(t, env.Nodes)
+	assert.Empty(t, env.CollapsedMap)
+}